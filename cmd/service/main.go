@@ -2,53 +2,136 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/iurikman/wallets/internal/config"
+	"github.com/iurikman/wallets/internal/grpcapi"
 	"github.com/iurikman/wallets/internal/rest"
 	"github.com/iurikman/wallets/internal/service"
 	"github.com/iurikman/wallets/internal/store"
 	migrate "github.com/rubenv/sql-migrate"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGQUIT, syscall.SIGHUP)
 	defer cancel()
 
-	cfg := config.NewConfig()
+	cfg, err := config.Load("wallets", os.Args[1:])
 
-	db, err := store.New(ctx, store.Config{
-		PGUser:     cfg.PostgresUser,
-		PGPass:     cfg.PostgresPassword,
-		PGHost:     cfg.PostgresHost,
-		PGPort:     cfg.PostgresPort,
-		PGDatabase: cfg.PostgresDatabase,
-	})
-	if err != nil {
-		log.Panicf("store.NewPostgres(context.Background(), store.ServerConfig{...} err: %v", err)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case err != nil:
+		log.Fatalf("invalid configuration:\n%v", err)
 	}
 
-	if err := db.Migrate(migrate.Up); err != nil {
-		log.Panicf("pgStore.Migrate: %v", err)
+	log.SetLevel(cfg.LogLevel)
+
+	db, err := store.New(ctx, cfg.Store())
+	if err != nil {
+		log.Panicf("store.New(ctx, cfg.Store()) err: %v", err)
 	}
 
-	log.Info("successful migration")
+	if cfg.MigrateOnStart {
+		if err := db.Migrate(migrate.Up); err != nil {
+			log.Panicf("pgStore.Migrate: %v", err)
+		}
+
+		log.Info("successful migration")
+	}
 
 	svc := service.New(db)
 
+	reconciler := service.NewReconciler(db, service.ReconcilerConfig{
+		Interval:      cfg.ReconcileInterval,
+		FreezeDrifted: cfg.ReconcileFreezeDrifted,
+	})
+
+	creditApplier := service.NewCreditApplier(db, service.CreditApplierConfig{
+		Interval:  cfg.HotWalletApplyInterval,
+		BatchSize: int(cfg.HotWalletApplyBatch),
+	})
+
 	srv, err := rest.NewServer(
-		rest.ServerConfig{BindAddress: cfg.BindAddress},
+		rest.ServerConfig{
+			BindAddress:     cfg.BindAddress,
+			AdminAPIKeys:    cfg.AdminAPIKeys,
+			ReadTimeout:     cfg.HTTPReadTimeout,
+			WriteTimeout:    cfg.HTTPWriteTimeout,
+			IdleTimeout:     cfg.HTTPIdleTimeout,
+			MaxBodyBytes:    int64(cfg.HTTPMaxBodyBytes),
+			ShutdownTimeout: cfg.ShutdownTimeout,
+			TLSCertFile:     cfg.TLSCertFile,
+			TLSKeyFile:      cfg.TLSKeyFile,
+			RateLimiter:     newRateLimiter(cfg.RateLimitBackend, db),
+			ClientRateLimit: cfg.ClientRateLimit(),
+			WalletRateLimit: cfg.WalletRateLimit(),
+			Deprecations:    cfg.APIDeprecations,
+		},
 		svc,
+		reconciler,
 	)
 	if err != nil {
 		log.Panicf("rest.NewServer(cfg) err: %v", err)
 	}
 
-	if err := srv.Start(ctx); err != nil {
-		log.Panicf("srv.Start(ctx) err: %v", err)
+	srv.AddReadinessCheck("postgres", db.Ping)
+	srv.AddReadinessCheck("migrations", db.CheckMigrations)
+	srv.AddReadinessCheck("reconciler", reconciler.Check)
+	srv.AddReadinessCheck("pending-credits", creditApplier.Check)
+
+	grpcSrv := grpcapi.NewServer(grpcapi.ServerConfig{
+		BindAddress:     cfg.GRPCBindAddress,
+		ShutdownTimeout: cfg.ShutdownTimeout,
+	}, svc)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	group.Go(func() error {
+		if err := srv.Start(groupCtx); err != nil {
+			return fmt.Errorf("srv.Start(ctx) err: %w", err)
+		}
+
+		return nil
+	})
+
+	group.Go(func() error {
+		if err := grpcSrv.Start(groupCtx); err != nil {
+			return fmt.Errorf("grpcSrv.Start(ctx) err: %w", err)
+		}
+
+		return nil
+	})
+
+	group.Go(func() error {
+		return reconciler.Start(groupCtx)
+	})
+
+	group.Go(func() error {
+		return creditApplier.Start(groupCtx)
+	})
+
+	if err := group.Wait(); err != nil {
+		log.Panicf("server stopped with error: %v", err)
 	}
 
 	log.Info("service stopped")
 }
+
+func newRateLimiter(backend string, db *store.Postgres) rest.RateLimiter {
+	switch backend {
+	case "memory":
+		return rest.NewMemoryRateLimiter()
+	case "postgres":
+		return db.RateLimiter()
+	default:
+		return nil
+	}
+}
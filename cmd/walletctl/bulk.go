@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/iurikman/wallets/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+func runImport(ctx context.Context, args []string) error {
+	flags := newFlagSet("import")
+	file := flags.String("file", "-", "file to import, - for stdin")
+	format := flags.String("format", formatCSV, "file format: csv or jsonl")
+	dryRun := flags.Bool("dry-run", false, "check the file against the database without saving")
+	_ = flags.Parse(args)
+
+	in, closeIn, err := openInput(*file)
+	if err != nil {
+		return err
+	}
+
+	defer closeIn()
+
+	records, err := readWalletRecords(bufio.NewReader(in), *format)
+	if err != nil {
+		return err
+	}
+
+	wallets := make([]models.Wallet, 0, len(records))
+	for _, record := range records {
+		wallets = append(wallets, record.wallet())
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	if err := svc.ImportWallets(ctx, wallets, *dryRun); err != nil {
+		var itemErr *models.BatchItemError
+		if errors.As(err, &itemErr) {
+			return fmt.Errorf("line %d: %w", records[itemErr.Index].line, itemErr.Err)
+		}
+
+		return fmt.Errorf("svc.ImportWallets() err: %w", err)
+	}
+
+	if *dryRun {
+		log.Infof("%d wallets can be imported, nothing was saved", len(wallets))
+
+		return nil
+	}
+
+	log.Infof("imported %d wallets", len(wallets))
+
+	return nil
+}
+
+func runExport(ctx context.Context, args []string) error {
+	flags := newFlagSet("export")
+	file := flags.String("file", "-", "file to write, - for stdout")
+	format := flags.String("format", formatCSV, "file format: csv or jsonl")
+	_ = flags.Parse(args)
+
+	out, closeOut, err := createOutput(*file)
+	if err != nil {
+		return err
+	}
+
+	defer closeOut()
+
+	w := bufio.NewWriter(out)
+
+	encoder, err := newWalletEncoder(w, *format)
+	if err != nil {
+		return err
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	var exported int
+
+	err = svc.ExportWallets(ctx, func(wallet models.Wallet) error {
+		exported++
+
+		return encoder.encode(newWalletRecord(wallet))
+	})
+	if err != nil {
+		return fmt.Errorf("svc.ExportWallets() err: %w", err)
+	}
+
+	if err := encoder.flush(); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing %s err: %w", *file, err)
+	}
+
+	log.Infof("exported %d wallets", exported)
+
+	return nil
+}
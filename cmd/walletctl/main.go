@@ -0,0 +1,143 @@
+// Command walletctl runs administrative tasks against the wallets database. It reads the same
+// configuration as the service.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/config"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/service"
+	"github.com/iurikman/wallets/internal/store"
+	log "github.com/sirupsen/logrus"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+//nolint:gochecknoglobals
+var commands = []command{
+	{name: "migrate", summary: "apply, roll back or list database migrations", run: group("migrate", migrateCommands)},
+	{name: "wallet", summary: "create, inspect, freeze and close wallets", run: group("wallet", walletCommands)},
+	{name: "deposit", summary: "add funds to a wallet", run: runDeposit},
+	{name: "withdraw", summary: "remove funds from a wallet", run: runWithdraw},
+	{name: "history", summary: "list the transactions of a wallet, newest first", run: runHistory},
+	{name: "reconcile", summary: "check wallet balances against their history", run: runReconcile},
+	{name: "import", summary: "create wallets with opening balances from a CSV or JSONL file", run: runImport},
+	{name: "export", summary: "write all wallets to a CSV or JSONL file", run: runExport},
+}
+
+var errUnknownCommand = errors.New("unknown command")
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGQUIT, syscall.SIGHUP)
+	defer cancel()
+
+	// Operators act on what they read, so reads never go to a lagging replica.
+	err := group("walletctl", commands)(models.WithReadYourWrites(ctx), os.Args[1:])
+
+	switch {
+	case errors.Is(err, errUnknownCommand):
+		os.Exit(2)
+	case err != nil:
+		log.Fatal(err)
+	}
+}
+
+// group dispatches to the subcommand named by the first argument.
+func group(name string, subcommands []command) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			for _, cmd := range subcommands {
+				if cmd.name == args[0] {
+					if err := cmd.run(ctx, args[1:]); err != nil {
+						return fmt.Errorf("%s: %w", cmd.name, err)
+					}
+
+					return nil
+				}
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", name)
+
+		for _, cmd := range subcommands {
+			fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+		}
+
+		return errUnknownCommand
+	}
+}
+
+// newStore connects to the database configured the same way as the service, from the
+// CONFIG_FILE file and the environment.
+func newStore(ctx context.Context) (*store.Postgres, error) {
+	cfg, err := config.Load("walletctl", nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	log.SetLevel(cfg.LogLevel)
+
+	db, err := store.New(ctx, cfg.Store())
+	if err != nil {
+		return nil, fmt.Errorf("store.New() err: %w", err)
+	}
+
+	return db, nil
+}
+
+func newService(ctx context.Context) (*service.Service, func(), error) {
+	db, err := newStore(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return service.New(db), db.Close, nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("writing output err: %w", err)
+	}
+
+	return nil
+}
+
+// uuidFlag is a flag holding a wallet or transaction ID.
+type uuidFlag struct {
+	value uuid.UUID
+}
+
+func (f *uuidFlag) String() string {
+	return f.value.String()
+}
+
+func (f *uuidFlag) Set(s string) error {
+	value, err := uuid.Parse(s)
+	if err != nil {
+		return fmt.Errorf("uuid.Parse() err: %w", err)
+	}
+
+	f.value = value
+
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("walletctl "+name, flag.ExitOnError)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/iurikman/wallets/internal/store"
+	migrate "github.com/rubenv/sql-migrate"
+	log "github.com/sirupsen/logrus"
+)
+
+//nolint:gochecknoglobals
+var migrateCommands = []command{
+	{name: "up", summary: "apply pending migrations", run: runMigrateUp},
+	{name: "down", summary: "roll back applied migrations, the latest one by default", run: runMigrateDown},
+	{name: "status", summary: "list migrations and when they were applied", run: runMigrateStatus},
+}
+
+func runMigrateUp(ctx context.Context, args []string) error {
+	return runMigrate(ctx, "migrate up", migrate.Up, 0, args)
+}
+
+func runMigrateDown(ctx context.Context, args []string) error {
+	return runMigrate(ctx, "migrate down", migrate.Down, 1, args)
+}
+
+func runMigrate(
+	ctx context.Context,
+	name string,
+	direction migrate.MigrationDirection,
+	defaultMax int,
+	args []string,
+) error {
+	flags := newFlagSet(name)
+	maxMigrations := flags.Int("n", defaultMax, "number of migrations to apply at most, 0 for all")
+	dryRun := flags.Bool("dry-run", false, "print the SQL of the migrations instead of running it")
+	allowDestructive := flags.Bool("allow-destructive", false,
+		"run migrations that drop tables or columns, losing their data")
+	_ = flags.Parse(args)
+
+	db, err := newStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	if *dryRun {
+		plan, err := db.PlanMigrations(direction, *maxMigrations)
+		if err != nil {
+			return err
+		}
+
+		printMigrationPlan(os.Stdout, plan)
+
+		return nil
+	}
+
+	applied, err := db.ApplyMigrations(ctx, direction, store.MigrateOptions{
+		Max:              *maxMigrations,
+		AllowDestructive: *allowDestructive,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("applied %d migrations", applied)
+
+	return nil
+}
+
+func printMigrationPlan(w io.Writer, plan []store.PlannedMigration) {
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "-- no migrations to apply")
+	}
+
+	for _, m := range plan {
+		fmt.Fprintf(w, "-- %s", m.ID)
+
+		if m.Destructive {
+			fmt.Fprint(w, " (destructive, needs -allow-destructive)")
+		}
+
+		fmt.Fprintln(w)
+
+		for _, query := range m.Queries {
+			fmt.Fprintln(w, strings.TrimSpace(query))
+		}
+
+		fmt.Fprintln(w)
+	}
+}
+
+func runMigrateStatus(ctx context.Context, args []string) error {
+	_ = newFlagSet("migrate status").Parse(args)
+
+	db, err := newStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	states, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			pending++
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d applied, %d pending\n", len(states)-pending, pending)
+
+	return printJSON(states)
+}
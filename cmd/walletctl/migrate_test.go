@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iurikman/wallets/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintMigrationPlan(t *testing.T) {
+	t.Run("nothing to apply", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		printMigrationPlan(&buf, nil)
+		require.Equal(t, "-- no migrations to apply\n", buf.String())
+	})
+
+	t.Run("migrations", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		printMigrationPlan(&buf, []store.PlannedMigration{
+			{ID: "1_wallets.sql", Queries: []string{"CREATE TABLE wallets (id uuid);\n"}},
+			{ID: "2_drop.sql", Queries: []string{"  DROP TABLE wallets;"}, Destructive: true},
+		})
+		require.Equal(t, "-- 1_wallets.sql\nCREATE TABLE wallets (id uuid);\n\n"+
+			"-- 2_drop.sql (destructive, needs -allow-destructive)\nDROP TABLE wallets;\n\n", buf.String())
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/iurikman/wallets/internal/service"
+)
+
+var errBalancesDrifted = errors.New("wallet balances drifted from their history")
+
+// runReconcile runs one reconciliation and prints its report. It fails when a wallet has drifted,
+// so it can be used in scripts.
+func runReconcile(ctx context.Context, args []string) error {
+	flags := newFlagSet("reconcile")
+	freeze := flags.Bool("freeze", false, "freeze the wallets found to have drifted")
+	_ = flags.Parse(args)
+
+	db, err := newStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	report, err := service.NewReconciler(db, service.ReconcilerConfig{FreezeDrifted: *freeze}).Reconcile(ctx)
+	if err != nil {
+		return fmt.Errorf("reconciler.Reconcile() err: %w", err)
+	}
+
+	if err := printJSON(report); err != nil {
+		return err
+	}
+
+	if len(report.Drifts) > 0 {
+		return fmt.Errorf("%w: %d wallets", errBalancesDrifted, len(report.Drifts))
+	}
+
+	return nil
+}
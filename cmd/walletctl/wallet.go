@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+//nolint:gochecknoglobals
+var walletCommands = []command{
+	{name: "create", summary: "create a wallet with zero balance", run: runWalletCreate},
+	{name: "get", summary: "show a wallet", run: runWalletGet},
+	{name: "freeze", summary: "block deposits and withdrawals of a wallet", run: runWalletFreeze},
+	{name: "unfreeze", summary: "allow deposits and withdrawals of a frozen wallet again", run: runWalletUnfreeze},
+	{name: "hot", summary: "queue deposits of a busy wallet and apply them in batches", run: runWalletHot},
+	{name: "cold", summary: "apply deposits of a hot wallet immediately again", run: runWalletCold},
+	{name: "close", summary: "delete a wallet with zero balance, keeping its history", run: runWalletClose},
+}
+
+var (
+	errWalletRequired = errors.New("-wallet is required")
+	errReasonRequired = errors.New("-reason is required")
+)
+
+// parseWalletFlags parses args with a required -wallet flag.
+func parseWalletFlags(flags *flag.FlagSet, args []string) (uuidFlag, error) {
+	var walletID uuidFlag
+
+	flags.Var(&walletID, "wallet", "wallet ID")
+	_ = flags.Parse(args)
+
+	if walletID.value == uuid.Nil {
+		return walletID, errWalletRequired
+	}
+
+	return walletID, nil
+}
+
+func runWalletCreate(ctx context.Context, args []string) error {
+	flags := newFlagSet("wallet create")
+	externalRef := flags.String("external-ref", "", "ID of the wallet in another system")
+	labels := flags.String("labels", "", "comma-separated labels of the wallet")
+	_ = flags.Parse(args)
+
+	var attrs models.WalletAttributes
+
+	if *externalRef != "" {
+		attrs.ExternalRef = externalRef
+	}
+
+	if *labels != "" {
+		split := strings.Split(*labels, ",")
+		attrs.Labels = &split
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	wallet, err := svc.CreateWallet(ctx, attrs)
+	if err != nil {
+		return fmt.Errorf("svc.CreateWallet() err: %w", err)
+	}
+
+	return printJSON(wallet)
+}
+
+func runWalletGet(ctx context.Context, args []string) error {
+	walletID, err := parseWalletFlags(newFlagSet("wallet get"), args)
+	if err != nil {
+		return err
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	wallet, err := svc.GetWallet(ctx, walletID.value)
+	if err != nil {
+		return fmt.Errorf("svc.GetWallet() err: %w", err)
+	}
+
+	return printJSON(wallet)
+}
+
+func runWalletFreeze(ctx context.Context, args []string) error {
+	return setWalletFrozen(ctx, "wallet freeze", args, true)
+}
+
+func runWalletUnfreeze(ctx context.Context, args []string) error {
+	return setWalletFrozen(ctx, "wallet unfreeze", args, false)
+}
+
+func setWalletFrozen(ctx context.Context, name string, args []string, frozen bool) error {
+	walletID, err := parseWalletFlags(newFlagSet(name), args)
+	if err != nil {
+		return err
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	wallet, err := svc.SetWalletFrozen(ctx, walletID.value, frozen)
+	if err != nil {
+		return fmt.Errorf("svc.SetWalletFrozen() err: %w", err)
+	}
+
+	return printJSON(wallet)
+}
+
+func runWalletHot(ctx context.Context, args []string) error {
+	return setWalletHot(ctx, "wallet hot", args, true)
+}
+
+func runWalletCold(ctx context.Context, args []string) error {
+	return setWalletHot(ctx, "wallet cold", args, false)
+}
+
+// setWalletHot switches the deposit mode of a wallet. Credits queued while it was hot are still
+// applied after it is switched back.
+func setWalletHot(ctx context.Context, name string, args []string, hot bool) error {
+	walletID, err := parseWalletFlags(newFlagSet(name), args)
+	if err != nil {
+		return err
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	wallet, err := svc.SetWalletHot(ctx, walletID.value, hot)
+	if err != nil {
+		return fmt.Errorf("svc.SetWalletHot() err: %w", err)
+	}
+
+	return printJSON(wallet)
+}
+
+func runWalletClose(ctx context.Context, args []string) error {
+	walletID, err := parseWalletFlags(newFlagSet("wallet close"), args)
+	if err != nil {
+		return err
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	wallet, err := svc.CloseWallet(ctx, walletID.value)
+	if err != nil {
+		return fmt.Errorf("svc.CloseWallet() err: %w", err)
+	}
+
+	return printJSON(wallet)
+}
+
+func runDeposit(ctx context.Context, args []string) error {
+	return changeBalance(ctx, "deposit", models.OperationDeposit, args)
+}
+
+func runWithdraw(ctx context.Context, args []string) error {
+	return changeBalance(ctx, "withdraw", models.OperationWithdraw, args)
+}
+
+// changeBalance runs a deposit or withdrawal on behalf of an operator, who must give a reason. The
+// reason is recorded with the transaction.
+func changeBalance(ctx context.Context, name, operationType string, args []string) error {
+	flags := newFlagSet(name)
+	amount := flags.Float64("amount", 0, "amount to move, greater than zero")
+	reason := flags.String("reason", "", "why the balance is changed, recorded with the transaction")
+
+	walletID, err := parseWalletFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *reason == "" {
+		return errReasonRequired
+	}
+
+	transaction := models.Transaction{
+		WalletID:      walletID.value,
+		Amount:        *amount,
+		OperationType: operationType,
+		Reason:        *reason,
+	}
+
+	if err := transaction.Validate(); err != nil {
+		return fmt.Errorf("transaction.Validate() err: %w", err)
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	var result *models.TransactionResult
+
+	if operationType == models.OperationDeposit {
+		result, err = svc.Deposit(ctx, transaction)
+	} else {
+		result, err = svc.Withdraw(ctx, transaction)
+	}
+
+	if err != nil {
+		return fmt.Errorf("changing balance err: %w", err)
+	}
+
+	return printJSON(result)
+}
+
+func runHistory(ctx context.Context, args []string) error {
+	flags := newFlagSet("history")
+	limit := flags.Int("limit", 0, fmt.Sprintf("page size, at most %d", models.MaxHistoryLimit))
+	cursor := flags.String("cursor", "", "nextCursor of the previous page")
+
+	walletID, err := parseWalletFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	params := models.HistoryParams{Limit: *limit}
+
+	if *cursor != "" {
+		if params.After, err = models.ParseTransactionCursor(*cursor); err != nil {
+			return fmt.Errorf("-cursor: %w", err)
+		}
+	}
+
+	svc, closeDB, err := newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer closeDB()
+
+	page, err := svc.ListTransactions(ctx, walletID.value, params)
+	if err != nil {
+		return fmt.Errorf("svc.ListTransactions() err: %w", err)
+	}
+
+	output := struct {
+		Transactions []models.TransactionResult `json:"transactions"`
+		NextCursor   string                     `json:"nextCursor,omitempty"`
+	}{Transactions: page.Transactions}
+
+	if page.NextCursor != nil {
+		output.NextCursor = page.NextCursor.Encode()
+	}
+
+	return printJSON(output)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+var (
+	errUnknownFormat   = errors.New("format must be one of csv, jsonl")
+	errNoBalanceColumn = errors.New("csv header has no balance column")
+)
+
+// walletRecord is a wallet in import and export files. CSV files have the columns id, balance,
+// created_at, frozen, hot, version, external_ref, labels (comma-separated) and metadata (a JSON
+// object); JSONL records the fields of the same names in camel case. Only the balance is required
+// on import.
+type walletRecord struct {
+	ID          uuid.UUID       `json:"id"`
+	Balance     float64         `json:"balance"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	Frozen      bool            `json:"frozen,omitempty"`
+	Hot         bool            `json:"hot,omitempty"`
+	Version     int64           `json:"version,omitempty"`
+	ExternalRef string          `json:"externalRef,omitempty"`
+	Labels      []string        `json:"labels,omitempty"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+
+	// line is the line of the record in the imported file.
+	line int
+}
+
+//nolint:gochecknoglobals
+var csvColumns = []string{"id", "balance", "created_at", "frozen", "hot", "version", "external_ref", "labels", "metadata"}
+
+func newWalletRecord(wallet models.Wallet) walletRecord {
+	return walletRecord{
+		ID:          wallet.ID,
+		Balance:     wallet.Balance,
+		CreatedAt:   wallet.CreatedAt,
+		Frozen:      wallet.Frozen,
+		Hot:         wallet.Hot,
+		Version:     wallet.Version,
+		ExternalRef: wallet.ExternalRef,
+		Labels:      wallet.Labels,
+		Metadata:    wallet.Metadata,
+	}
+}
+
+func (w *walletRecord) wallet() models.Wallet {
+	return models.Wallet{
+		ID:          w.ID,
+		Balance:     w.Balance,
+		CreatedAt:   w.CreatedAt,
+		Frozen:      w.Frozen,
+		Hot:         w.Hot,
+		Version:     w.Version,
+		ExternalRef: w.ExternalRef,
+		Labels:      w.Labels,
+		Metadata:    w.Metadata,
+	}
+}
+
+// readWalletRecords parses the whole file first, so that a malformed record is reported before
+// anything is written to the database.
+func readWalletRecords(r io.Reader, format string) ([]walletRecord, error) {
+	switch format {
+	case formatCSV:
+		return readCSVWalletRecords(r)
+	case formatJSONL:
+		return readJSONLWalletRecords(r)
+	default:
+		return nil, errUnknownFormat
+	}
+}
+
+func readCSVWalletRecords(r io.Reader) ([]walletRecord, error) {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header err: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := columns["balance"]; !ok {
+		return nil, errNoBalanceColumn
+	}
+
+	records := make([]walletRecord, 0)
+
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading csv err: %w", err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		record := walletRecord{line: line}
+		column := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+
+			return ""
+		}
+
+		if err := record.parse(column); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		records = append(records, record)
+	}
+}
+
+// parse sets the record from the CSV columns, which are empty when the file does not have them.
+func (w *walletRecord) parse(column func(name string) string) error {
+	var err error
+
+	if id := column("id"); id != "" {
+		if w.ID, err = uuid.Parse(id); err != nil {
+			return fmt.Errorf("id: %w", err)
+		}
+	}
+
+	if w.Balance, err = strconv.ParseFloat(column("balance"), 64); err != nil {
+		return fmt.Errorf("balance: %w", err)
+	}
+
+	if createdAt := column("created_at"); createdAt != "" {
+		if w.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+			return fmt.Errorf("created_at: %w", err)
+		}
+	}
+
+	if frozen := column("frozen"); frozen != "" {
+		if w.Frozen, err = strconv.ParseBool(frozen); err != nil {
+			return fmt.Errorf("frozen: %w", err)
+		}
+	}
+
+	if hot := column("hot"); hot != "" {
+		if w.Hot, err = strconv.ParseBool(hot); err != nil {
+			return fmt.Errorf("hot: %w", err)
+		}
+	}
+
+	if version := column("version"); version != "" {
+		if w.Version, err = strconv.ParseInt(version, 10, 64); err != nil {
+			return fmt.Errorf("version: %w", err)
+		}
+	}
+
+	w.ExternalRef = column("external_ref")
+
+	if labels := column("labels"); labels != "" {
+		w.Labels = strings.Split(labels, ",")
+	}
+
+	if metadata := column("metadata"); metadata != "" {
+		w.Metadata = json.RawMessage(metadata)
+	}
+
+	return nil
+}
+
+func readJSONLWalletRecords(r io.Reader) ([]walletRecord, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	records := make([]walletRecord, 0)
+
+	for line := 1; ; line++ {
+		var record walletRecord
+
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", line, err)
+		}
+
+		record.line = line
+		records = append(records, record)
+	}
+}
+
+// walletEncoder writes wallet records in one export format.
+type walletEncoder interface {
+	encode(record walletRecord) error
+	flush() error
+}
+
+func newWalletEncoder(w io.Writer, format string) (walletEncoder, error) {
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, fmt.Errorf("writing csv header err: %w", err)
+		}
+
+		return &csvWalletEncoder{w: cw}, nil
+	case formatJSONL:
+		return &jsonlWalletEncoder{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, errUnknownFormat
+	}
+}
+
+type csvWalletEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvWalletEncoder) encode(record walletRecord) error {
+	err := e.w.Write([]string{
+		record.ID.String(),
+		strconv.FormatFloat(record.Balance, 'f', -1, 64),
+		record.CreatedAt.Format(time.RFC3339Nano),
+		strconv.FormatBool(record.Frozen),
+		strconv.FormatBool(record.Hot),
+		strconv.FormatInt(record.Version, 10),
+		record.ExternalRef,
+		strings.Join(record.Labels, ","),
+		string(record.Metadata),
+	})
+	if err != nil {
+		return fmt.Errorf("writing csv record err: %w", err)
+	}
+
+	return nil
+}
+
+func (e *csvWalletEncoder) flush() error {
+	e.w.Flush()
+
+	if err := e.w.Error(); err != nil {
+		return fmt.Errorf("flushing csv err: %w", err)
+	}
+
+	return nil
+}
+
+type jsonlWalletEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlWalletEncoder) encode(record walletRecord) error {
+	if err := e.enc.Encode(record); err != nil {
+		return fmt.Errorf("writing jsonl record err: %w", err)
+	}
+
+	return nil
+}
+
+func (e *jsonlWalletEncoder) flush() error {
+	return nil
+}
+
+func openInput(name string) (io.Reader, func(), error) {
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("os.Open() err: %w", err)
+	}
+
+	return f, func() { _ = f.Close() }, nil
+}
+
+func createOutput(name string) (io.Writer, func(), error) {
+	if name == "-" {
+		return os.Stdout, func() {}, nil
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("os.Create() err: %w", err)
+	}
+
+	return f, func() { _ = f.Close() }, nil
+}
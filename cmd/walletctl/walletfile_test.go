@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletRecordsRoundTrip(t *testing.T) {
+	records := []walletRecord{
+		{ID: uuid.New(), Balance: 10.25, CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)},
+		{ID: uuid.New(), Balance: 0, CreatedAt: time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)},
+		{
+			ID: uuid.New(), Balance: 3, CreatedAt: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC),
+			Frozen: true, Hot: true, Version: 7, ExternalRef: "acc, 42", Labels: []string{"merchant", "promo"},
+			Metadata: json.RawMessage(`{"name":"Shop","tags":["a","b"]}`),
+		},
+	}
+
+	// The CSV header takes the first line.
+	firstLines := map[string]int{formatCSV: 2, formatJSONL: 1}
+
+	for format, firstLine := range firstLines {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			encoder, err := newWalletEncoder(&buf, format)
+			require.NoError(t, err)
+
+			for _, record := range records {
+				require.NoError(t, encoder.encode(record))
+			}
+
+			require.NoError(t, encoder.flush())
+
+			read, err := readWalletRecords(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, read, len(records))
+
+			for i, record := range read {
+				require.Equal(t, firstLine+i, record.line)
+
+				record.line = 0
+				require.Equal(t, records[i], record)
+			}
+		})
+	}
+}
+
+func TestReadCSVWalletRecords(t *testing.T) {
+	t.Run("optional columns", func(t *testing.T) {
+		records, err := readWalletRecords(strings.NewReader("balance\n5\n0.5\n"), formatCSV)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, uuid.Nil, records[0].ID)
+		require.Equal(t, 0.5, records[1].Balance)
+		require.Equal(t, 3, records[1].line)
+	})
+
+	t.Run("malformed record reports its line", func(t *testing.T) {
+		_, err := readWalletRecords(strings.NewReader("id,balance\n,1\n,ten\n"), formatCSV)
+		require.ErrorContains(t, err, "line 3: balance")
+	})
+
+	t.Run("attribute columns", func(t *testing.T) {
+		records, err := readWalletRecords(strings.NewReader(
+			"balance,frozen,hot,version,external_ref,labels,metadata\n1,true,false,4,acc-1,\"a,b\",\"{\"\"k\"\": 1}\"\n"), formatCSV)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.True(t, records[0].Frozen)
+		require.False(t, records[0].Hot)
+		require.Equal(t, int64(4), records[0].Version)
+		require.Equal(t, "acc-1", records[0].ExternalRef)
+		require.Equal(t, []string{"a", "b"}, records[0].Labels)
+		require.JSONEq(t, `{"k": 1}`, string(records[0].Metadata))
+	})
+
+	t.Run("malformed attribute reports its line", func(t *testing.T) {
+		_, err := readWalletRecords(strings.NewReader("balance,frozen\n1,yes\n"), formatCSV)
+		require.ErrorContains(t, err, "line 2: frozen")
+	})
+
+	t.Run("balance column is required", func(t *testing.T) {
+		_, err := readWalletRecords(strings.NewReader("id\n"), formatCSV)
+		require.ErrorIs(t, err, errNoBalanceColumn)
+	})
+}
+
+func TestWalletRecordKeepsAttributes(t *testing.T) {
+	wallet := models.Wallet{
+		ID: uuid.New(), Balance: 3, CreatedAt: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC),
+		Frozen: true, Hot: true, Version: 7, ExternalRef: "acc-42", Labels: []string{"merchant"},
+		Metadata: json.RawMessage(`{"name":"Shop"}`),
+	}
+
+	record := newWalletRecord(wallet)
+	require.Equal(t, wallet, record.wallet())
+}
@@ -1,38 +1,615 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/store"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	BindAddress string
+	BindAddress     string
+	GRPCBindAddress string
+	AdminAPIKeys    []string
 
-	PostgresHost     string
-	PostgresPort     string
-	PostgresDatabase string
-	PostgresUser     string
-	PostgresPassword string
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+	HTTPMaxBodyBytes int32
+	ShutdownTimeout  time.Duration
+	TLSCertFile      string
+	TLSKeyFile       string
+
+	// APIDeprecations are announced on the responses of their routes.
+	APIDeprecations []models.Deprecation
+
+	LogLevel log.Level
+
+	// MigrateOnStart applies pending migrations before the service starts serving. Replicas
+	// started together take turns, so it is safe to enable on all of them.
+	MigrateOnStart bool
+
+	ReconcileInterval      time.Duration
+	ReconcileFreezeDrifted bool
+
+	HotWalletApplyInterval time.Duration
+	HotWalletApplyBatch    int32
+
+	// RateLimitBackend keeps the rate limit buckets: none, memory, which limits every replica on
+	// its own, or postgres, which shares them between replicas.
+	RateLimitBackend         string
+	RateLimitClientPerMinute int32
+	RateLimitClientBurst     int32
+	RateLimitWalletPerMinute int32
+	RateLimitWalletBurst     int32
+
+	// PostgresDSN replaces the connection settings from PostgresHost to PostgresSSLKey.
+	PostgresDSN              string
+	PostgresHost             string
+	PostgresPort             string
+	PostgresDatabase         string
+	PostgresUser             string
+	PostgresPassword         string
+	PostgresSSLMode          string
+	PostgresSSLRootCert      string
+	PostgresSSLCert          string
+	PostgresSSLKey           string
+	PostgresMaxConns         int32
+	PostgresMinConns         int32
+	PostgresMaxConnLifetime  time.Duration
+	PostgresMaxConnIdleTime  time.Duration
+	PostgresConnectTimeout   time.Duration
+	PostgresStatementTimeout time.Duration
+	PostgresApplicationName  string
+	PostgresReplicaDSNs      []string
+	PostgresReplicaMaxLag    time.Duration
+	PostgresTxIsolation      string
+	PostgresTxMaxRetries     int32
+	PostgresTxRetryDelay     time.Duration
+	PostgresTxRetryMaxDelay  time.Duration
+}
+
+var (
+	errRequired     = errors.New("value is required")
+	errUnknownKey   = errors.New("unknown setting")
+	errNotAllowed   = errors.New("value is not allowed")
+	errNegative     = errors.New("value is negative")
+	errInconsistent = errors.New("settings are inconsistent")
+)
+
+// setting is one configuration value. Its name is both the environment variable and the key in a
+// config file; the command-line flag is the name in lower case with dashes.
+type setting struct {
+	name     string
+	usage    string
+	value    string
+	required bool
+	parse    func(value string) error
+}
+
+func (s setting) flagName() string {
+	return strings.ToLower(strings.ReplaceAll(s.name, "_", "-"))
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{name: "BIND_ADDRESS", usage: "HTTP listen address", value: ":8080", required: true,
+			parse: address(&c.BindAddress)},
+		{name: "GRPC_BIND_ADDRESS", usage: "gRPC listen address", value: ":9090", required: true,
+			parse: address(&c.GRPCBindAddress)},
+		{name: "ADMIN_API_KEYS", usage: "comma-separated API keys of the admin endpoints",
+			parse: list(&c.AdminAPIKeys)},
+		{name: "HTTP_READ_TIMEOUT", usage: "time to read a whole HTTP request, 0 for none", value: "30s",
+			parse: duration(&c.HTTPReadTimeout)},
+		{name: "HTTP_WRITE_TIMEOUT", usage: "time to write an HTTP response, 0 for none, which suits long statements",
+			value: "0", parse: duration(&c.HTTPWriteTimeout)},
+		{name: "HTTP_IDLE_TIMEOUT", usage: "time a keep-alive connection may stay idle", value: "2m",
+			parse: duration(&c.HTTPIdleTimeout)},
+		{name: "HTTP_MAX_BODY_BYTES", usage: "size of the largest request body accepted", value: "1048576",
+			parse: count(&c.HTTPMaxBodyBytes)},
+		{name: "API_DEPRECATIONS", usage: `comma-separated deprecated routes as "METHOD ROUTE [SUNSET DATE]", e.g. "GET /api/v1/wallets/{id} 2027-06-30"`,
+			parse: deprecations(&c.APIDeprecations)},
+		{name: "SHUTDOWN_TIMEOUT", usage: "time given to in-flight requests on shutdown", value: "5s",
+			parse: duration(&c.ShutdownTimeout)},
+		{name: "TLS_CERT_FILE", usage: "certificate file, serves HTTPS together with TLS_KEY_FILE",
+			parse: file(&c.TLSCertFile)},
+		{name: "TLS_KEY_FILE", usage: "private key file of TLS_CERT_FILE",
+			parse: file(&c.TLSKeyFile)},
+		{name: "LOG_LEVEL", usage: "one of panic, fatal, error, warn, info, debug, trace", value: "info",
+			parse: logLevel(&c.LogLevel)},
+		{name: "MIGRATE_ON_START", usage: "apply pending database migrations on start", value: "true",
+			parse: boolean(&c.MigrateOnStart)},
+		{name: "RECONCILE_INTERVAL", usage: "interval of the reconciliation job, 0 disables it", value: "1h",
+			parse: duration(&c.ReconcileInterval)},
+		{name: "RECONCILE_FREEZE_DRIFTED", usage: "freeze wallets found to have drifted", value: "false",
+			parse: boolean(&c.ReconcileFreezeDrifted)},
+		{name: "HOT_WALLET_APPLY_INTERVAL", usage: "interval of applying hot wallet deposits, 0 disables it", value: "1s",
+			parse: duration(&c.HotWalletApplyInterval)},
+		{name: "HOT_WALLET_APPLY_BATCH", usage: "hot wallet deposits applied to a wallet at most in one transaction",
+			value: "1000", parse: count(&c.HotWalletApplyBatch)},
+		{name: "RATE_LIMIT_BACKEND", usage: "where rate limits are kept: none, memory or postgres to share them between replicas",
+			value: "memory", parse: oneOf(&c.RateLimitBackend, "none", "memory", "postgres")},
+		{name: "RATE_LIMIT_CLIENT_PER_MINUTE", usage: "API requests per minute of a client, 0 for no limit", value: "600",
+			parse: count(&c.RateLimitClientPerMinute)},
+		{name: "RATE_LIMIT_CLIENT_BURST", usage: "API requests a client may make at once", value: "100",
+			parse: count(&c.RateLimitClientBurst)},
+		{name: "RATE_LIMIT_WALLET_PER_MINUTE", usage: "withdrawals per minute from a wallet, 0 for no limit", value: "60",
+			parse: count(&c.RateLimitWalletPerMinute)},
+		{name: "RATE_LIMIT_WALLET_BURST", usage: "withdrawals from a wallet at once", value: "10",
+			parse: count(&c.RateLimitWalletBurst)},
+		{name: "POSTGRES_DSN", usage: "connection string used instead of the POSTGRES_HOST to POSTGRES_SSLKEY settings",
+			parse: text(&c.PostgresDSN)},
+		{name: "POSTGRES_HOST", usage: "database host, required without POSTGRES_DSN",
+			parse: text(&c.PostgresHost)},
+		{name: "POSTGRES_PORT", usage: "database port", value: "5432", required: true,
+			parse: port(&c.PostgresPort)},
+		{name: "POSTGRES_DATABASE", usage: "database name, required without POSTGRES_DSN",
+			parse: text(&c.PostgresDatabase)},
+		{name: "POSTGRES_USER", usage: "database user, required without POSTGRES_DSN",
+			parse: text(&c.PostgresUser)},
+		{name: "POSTGRES_PASSWORD", usage: "database password",
+			parse: text(&c.PostgresPassword)},
+		{name: "POSTGRES_SSLMODE", usage: "one of disable, allow, prefer, require, verify-ca, verify-full",
+			value: "disable", parse: oneOf(&c.PostgresSSLMode,
+				"disable", "allow", "prefer", "require", "verify-ca", "verify-full")},
+		{name: "POSTGRES_SSLROOTCERT", usage: "CA certificate file the server certificate is verified against",
+			parse: file(&c.PostgresSSLRootCert)},
+		{name: "POSTGRES_SSLCERT", usage: "client certificate file, used together with POSTGRES_SSLKEY",
+			parse: file(&c.PostgresSSLCert)},
+		{name: "POSTGRES_SSLKEY", usage: "private key file of POSTGRES_SSLCERT",
+			parse: file(&c.PostgresSSLKey)},
+		{name: "POSTGRES_MAX_CONNS", usage: "maximum size of the connection pool, 0 for the driver default",
+			value: "0", parse: count(&c.PostgresMaxConns)},
+		{name: "POSTGRES_MIN_CONNS", usage: "connections the pool keeps open when idle", value: "0",
+			parse: count(&c.PostgresMinConns)},
+		{name: "POSTGRES_MAX_CONN_LIFETIME", usage: "time after which a connection is replaced, 0 for the driver default",
+			value: "0", parse: duration(&c.PostgresMaxConnLifetime)},
+		{name: "POSTGRES_MAX_CONN_IDLE_TIME", usage: "time after which an idle connection is closed, 0 for the driver default",
+			value: "0", parse: duration(&c.PostgresMaxConnIdleTime)},
+		{name: "POSTGRES_CONNECT_TIMEOUT", usage: "time to establish a database connection, 0 for none",
+			value: "10s", parse: duration(&c.PostgresConnectTimeout)},
+		{name: "POSTGRES_STATEMENT_TIMEOUT", usage: "time after which a statement, migrations included, is aborted, 0 for none",
+			value: "0", parse: duration(&c.PostgresStatementTimeout)},
+		{name: "POSTGRES_APPLICATION_NAME", usage: "name of the connections in pg_stat_activity", value: "wallets",
+			parse: text(&c.PostgresApplicationName)},
+		{name: "POSTGRES_REPLICA_DSNS", usage: "comma-separated connection strings of read replicas for wallet and history reads",
+			parse: list(&c.PostgresReplicaDSNs)},
+		{name: "POSTGRES_REPLICA_MAX_LAG", usage: "replication lag beyond which reads skip a replica, 0 to not check the lag",
+			value: "5s", parse: duration(&c.PostgresReplicaMaxLag)},
+		{name: "POSTGRES_TX_ISOLATION", usage: "isolation of balance changes: read committed, repeatable read or serializable",
+			value: "read committed", parse: oneOf(&c.PostgresTxIsolation, "read committed", "repeatable read", "serializable")},
+		{name: "POSTGRES_TX_MAX_RETRIES", usage: "retries of a balance change after a serialization failure or deadlock",
+			value: "5", parse: count(&c.PostgresTxMaxRetries)},
+		{name: "POSTGRES_TX_RETRY_DELAY", usage: "backoff before the first retry, doubled for each next one", value: "10ms",
+			parse: duration(&c.PostgresTxRetryDelay)},
+		{name: "POSTGRES_TX_RETRY_MAX_DELAY", usage: "longest backoff between retries", value: "500ms",
+			parse: duration(&c.PostgresTxRetryMaxDelay)},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence: defaults, the config
+// file, non-empty environment variables and the command-line flags in args. The config file is
+// given by the -config flag or the CONFIG_FILE variable; it is a YAML mapping if its extension is
+// .yaml or .yml and a .env file otherwise. All invalid values are reported together.
+func Load(name string, args []string) (Config, error) {
+	var config Config
+
+	settings := config.settings()
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or .env file with settings")
+
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.name] = flags.String(s.flagName(), "", fmt.Sprintf("%s (%s)", s.usage, s.name))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return config, fmt.Errorf("flags.Parse() err: %w", err)
+	}
+
+	values := make(map[string]string, len(settings))
+	for _, s := range settings {
+		values[s.name] = s.value
+	}
+
+	if *configFile != "" {
+		fileValues, err := readFile(*configFile)
+		if err != nil {
+			return config, err
+		}
+
+		errs := make([]error, 0)
+
+		for key, value := range fileValues {
+			if _, ok := values[key]; !ok {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", *configFile, key, errUnknownKey))
+
+				continue
+			}
+
+			values[key] = value
+		}
+
+		if len(errs) > 0 {
+			return config, errors.Join(errs...)
+		}
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.name); value != "" {
+			values[s.name] = value
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flagName() == f.Name {
+				values[s.name] = *flagValues[s.name]
+			}
+		}
+	})
+
+	errs := make([]error, 0)
+
+	for _, s := range settings {
+		value := strings.TrimSpace(values[s.name])
+
+		if value == "" && s.required {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, errRequired))
+
+			continue
+		}
+
+		if err := s.parse(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+
+	errs = append(errs, config.validate()...)
+
+	if len(errs) > 0 {
+		return config, errors.Join(errs...)
+	}
+
+	return config, nil
+}
+
+// validate checks the settings that depend on each other.
+func (c *Config) validate() []error {
+	errs := make([]error, 0)
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together: %w", errInconsistent))
+	}
+
+	if c.PostgresDSN == "" {
+		required := []struct{ name, value string }{
+			{name: "POSTGRES_HOST", value: c.PostgresHost},
+			{name: "POSTGRES_DATABASE", value: c.PostgresDatabase},
+			{name: "POSTGRES_USER", value: c.PostgresUser},
+		}
+
+		for _, r := range required {
+			if r.value == "" {
+				errs = append(errs, fmt.Errorf("%s: %w without POSTGRES_DSN", r.name, errRequired))
+			}
+		}
+	}
+
+	if (c.PostgresSSLCert == "") != (c.PostgresSSLKey == "") {
+		errs = append(errs, fmt.Errorf("POSTGRES_SSLCERT and POSTGRES_SSLKEY must be set together: %w", errInconsistent))
+	}
+
+	if c.PostgresMaxConns > 0 && c.PostgresMinConns > c.PostgresMaxConns {
+		errs = append(errs, fmt.Errorf("POSTGRES_MIN_CONNS is above POSTGRES_MAX_CONNS: %w", errInconsistent))
+	}
+
+	if c.PostgresTxRetryDelay > c.PostgresTxRetryMaxDelay {
+		errs = append(errs, fmt.Errorf("POSTGRES_TX_RETRY_DELAY is above POSTGRES_TX_RETRY_MAX_DELAY: %w", errInconsistent))
+	}
+
+	if c.RateLimitClientPerMinute > 0 && c.RateLimitClientBurst == 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_CLIENT_BURST: %w while RATE_LIMIT_CLIENT_PER_MINUTE is set", errRequired))
+	}
+
+	if c.RateLimitWalletPerMinute > 0 && c.RateLimitWalletBurst == 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_WALLET_BURST: %w while RATE_LIMIT_WALLET_PER_MINUTE is set", errRequired))
+	}
+
+	if c.HotWalletApplyInterval > 0 && c.HotWalletApplyBatch == 0 {
+		errs = append(errs, fmt.Errorf("HOT_WALLET_APPLY_BATCH: %w while HOT_WALLET_APPLY_INTERVAL is set", errRequired))
+	}
+
+	return errs
+}
+
+// ClientRateLimit returns the limit of API requests of a client.
+func (c *Config) ClientRateLimit() models.RateLimit {
+	return perMinute(c.RateLimitClientPerMinute, c.RateLimitClientBurst)
+}
+
+// WalletRateLimit returns the limit of withdrawals from a wallet.
+func (c *Config) WalletRateLimit() models.RateLimit {
+	return perMinute(c.RateLimitWalletPerMinute, c.RateLimitWalletBurst)
+}
+
+func perMinute(requests, burst int32) models.RateLimit {
+	return models.RateLimit{Rate: float64(requests) / time.Minute.Seconds(), Burst: int(burst)}
+}
+
+// Store returns the database settings.
+func (c *Config) Store() store.Config {
+	return store.Config{
+		DSN:              c.PostgresDSN,
+		PGUser:           c.PostgresUser,
+		PGPass:           c.PostgresPassword,
+		PGHost:           c.PostgresHost,
+		PGPort:           c.PostgresPort,
+		PGDatabase:       c.PostgresDatabase,
+		SSLMode:          c.PostgresSSLMode,
+		SSLRootCert:      c.PostgresSSLRootCert,
+		SSLCert:          c.PostgresSSLCert,
+		SSLKey:           c.PostgresSSLKey,
+		MaxConns:         c.PostgresMaxConns,
+		MinConns:         c.PostgresMinConns,
+		MaxConnLifetime:  c.PostgresMaxConnLifetime,
+		MaxConnIdleTime:  c.PostgresMaxConnIdleTime,
+		ConnectTimeout:   c.PostgresConnectTimeout,
+		StatementTimeout: c.PostgresStatementTimeout,
+		ApplicationName:  c.PostgresApplicationName,
+		ReplicaDSNs:      c.PostgresReplicaDSNs,
+		ReplicaMaxLag:    c.PostgresReplicaMaxLag,
+		TxIsolation:      c.PostgresTxIsolation,
+		TxMaxRetries:     int(c.PostgresTxMaxRetries),
+		TxRetryDelay:     c.PostgresTxRetryDelay,
+		TxRetryMaxDelay:  c.PostgresTxRetryMaxDelay,
+	}
+}
+
+func readFile(path string) (map[string]string, error) {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return readYAML(path)
+	default:
+		values, err := godotenv.Read(path)
+		if err != nil {
+			return nil, fmt.Errorf("godotenv.Read(%s) err: %w", path, err)
+		}
+
+		return values, nil
+	}
 }
 
-func NewConfig() Config {
-	err := godotenv.Load("example.env")
+// readYAML reads a mapping of setting names to values. Lists are joined with commas.
+func readYAML(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		log.Panicf("Error loading example.env file")
+		return nil, fmt.Errorf("os.ReadFile(%s) err: %w", path, err)
+	}
+
+	var raw map[string]any
+
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal(%s) err: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+
+	for key, value := range raw {
+		if items, ok := value.([]any); ok {
+			parts := make([]string, 0, len(items))
+			for _, item := range items {
+				parts = append(parts, fmt.Sprint(item))
+			}
+
+			values[key] = strings.Join(parts, ",")
+
+			continue
+		}
+
+		if value != nil {
+			values[key] = fmt.Sprint(value)
+		}
+	}
+
+	return values, nil
+}
+
+func text(dest *string) func(string) error {
+	return func(value string) error {
+		*dest = value
+
+		return nil
+	}
+}
+
+func file(dest *string) func(string) error {
+	return func(value string) error {
+		if value != "" {
+			if _, err := os.Stat(value); err != nil {
+				return fmt.Errorf("os.Stat() err: %w", err)
+			}
+		}
+
+		*dest = value
+
+		return nil
+	}
+}
+
+func address(dest *string) func(string) error {
+	return func(value string) error {
+		_, portValue, err := net.SplitHostPort(value)
+		if err != nil {
+			return fmt.Errorf("net.SplitHostPort() err: %w", err)
+		}
+
+		if err := port(new(string))(portValue); err != nil {
+			return err
+		}
+
+		*dest = value
+
+		return nil
+	}
+}
+
+func port(dest *string) func(string) error {
+	return func(value string) error {
+		if _, err := strconv.ParseUint(value, 10, 16); err != nil {
+			return fmt.Errorf("invalid port %q: %w", value, errNotAllowed)
+		}
+
+		*dest = value
+
+		return nil
+	}
+}
+
+func oneOf(dest *string, allowed ...string) func(string) error {
+	return func(value string) error {
+		if !slices.Contains(allowed, value) {
+			return fmt.Errorf("%q: %w", value, errNotAllowed)
+		}
+
+		*dest = value
+
+		return nil
 	}
+}
+
+func list(dest *[]string) func(string) error {
+	return func(value string) error {
+		items := make([]string, 0)
+
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		*dest = items
+
+		return nil
+	}
+}
+
+func duration(dest *time.Duration) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("time.ParseDuration() err: %w", err)
+		}
+
+		if d < 0 {
+			return errNegative
+		}
+
+		*dest = d
+
+		return nil
+	}
+}
+
+func boolean(dest *bool) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("strconv.ParseBool() err: %w", err)
+		}
+
+		*dest = b
+
+		return nil
+	}
+}
+
+func count(dest *int32) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("strconv.ParseInt() err: %w", err)
+		}
 
-	log.Debug("environment variables loaded")
+		if n < 0 {
+			return errNegative
+		}
 
-	config := Config{
-		BindAddress:      os.Getenv("BIND_ADDRESS"),
-		PostgresHost:     os.Getenv("POSTGRES_HOST"),
-		PostgresPort:     os.Getenv("POSTGRES_PORT"),
-		PostgresDatabase: os.Getenv("POSTGRES_DATABASE"),
-		PostgresUser:     os.Getenv("POSTGRES_USER"),
-		PostgresPassword: os.Getenv("POSTGRES_PASSWORD"),
+		*dest = int32(n)
+
+		return nil
 	}
+}
+
+func deprecations(dest *[]models.Deprecation) func(string) error {
+	return func(value string) error {
+		var items []string
+		if err := list(&items)(value); err != nil {
+			return err
+		}
+
+		result := make([]models.Deprecation, 0, len(items))
+
+		for _, item := range items {
+			fields := strings.Fields(item)
+			if len(fields) < 2 || len(fields) > 3 || !strings.HasPrefix(fields[1], "/") {
+				return fmt.Errorf("%q: %w", item, errNotAllowed)
+			}
+
+			deprecation := models.Deprecation{Method: strings.ToUpper(fields[0]), Route: fields[1]}
+
+			if len(fields) == 3 {
+				sunset, err := time.Parse(time.DateOnly, fields[2])
+				if err != nil {
+					return fmt.Errorf("time.Parse(%q) err: %w", fields[2], err)
+				}
 
-	return config
+				deprecation.Sunset = sunset
+			}
+
+			result = append(result, deprecation)
+		}
+
+		*dest = result
+
+		return nil
+	}
+}
+
+func logLevel(dest *log.Level) func(string) error {
+	return func(value string) error {
+		level, err := log.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("log.ParseLevel() err: %w", err)
+		}
+
+		*dest = level
+
+		return nil
+	}
 }
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iurikman/wallets/internal/models"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv unsets every setting for the duration of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("CONFIG_FILE", "")
+
+	for _, s := range new(Config).settings() {
+		t.Setenv(s.name, "")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load("test", []string{
+		"-postgres-host", "db", "-postgres-database", "wallets", "-postgres-user", "admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, ":8080", cfg.BindAddress)
+	require.Equal(t, ":9090", cfg.GRPCBindAddress)
+	require.Equal(t, "5432", cfg.PostgresPort)
+	require.Equal(t, "disable", cfg.PostgresSSLMode)
+	require.Equal(t, time.Hour, cfg.ReconcileInterval)
+	require.Equal(t, time.Second, cfg.HotWalletApplyInterval)
+	require.Equal(t, int32(1000), cfg.HotWalletApplyBatch)
+	require.Equal(t, "memory", cfg.RateLimitBackend)
+	require.Equal(t, models.RateLimit{Rate: 10, Burst: 100}, cfg.ClientRateLimit())
+	require.Equal(t, models.RateLimit{Rate: 1, Burst: 10}, cfg.WalletRateLimit())
+	require.Equal(t, log.InfoLevel, cfg.LogLevel)
+	require.True(t, cfg.MigrateOnStart)
+	require.Empty(t, cfg.AdminAPIKeys)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "wallets.env", `
+POSTGRES_HOST=file-host
+POSTGRES_DATABASE=file-db
+POSTGRES_USER=file-user
+POSTGRES_PORT=6432
+`)
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("POSTGRES_DATABASE", "env-db")
+	t.Setenv("POSTGRES_USER", "env-user")
+
+	cfg, err := Load("test", []string{"-postgres-user", "flag-user"})
+	require.NoError(t, err)
+	require.Equal(t, "file-host", cfg.PostgresHost)
+	require.Equal(t, "6432", cfg.PostgresPort)
+	require.Equal(t, "env-db", cfg.PostgresDatabase)
+	require.Equal(t, "flag-user", cfg.PostgresUser)
+}
+
+func TestLoadDSN(t *testing.T) {
+	clearEnv(t)
+
+	t.Setenv("POSTGRES_DSN", "postgres://admin:secret@db:5432/wallets")
+
+	cfg, err := Load("test", []string{"-postgres-statement-timeout", "30s"})
+	require.NoError(t, err)
+	require.Equal(t, "postgres://admin:secret@db:5432/wallets", cfg.Store().DSN)
+	require.Equal(t, 30*time.Second, cfg.Store().StatementTimeout)
+	require.Equal(t, "wallets", cfg.Store().ApplicationName)
+}
+
+func TestLoadYAML(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "wallets.yaml", `
+POSTGRES_HOST: db
+POSTGRES_DATABASE: wallets
+POSTGRES_USER: admin
+POSTGRES_MAX_CONNS: 20
+RECONCILE_FREEZE_DRIFTED: true
+ADMIN_API_KEYS:
+  - first
+  - second
+`)
+
+	cfg, err := Load("test", []string{"-config", path})
+	require.NoError(t, err)
+	require.Equal(t, int32(20), cfg.PostgresMaxConns)
+	require.True(t, cfg.ReconcileFreezeDrifted)
+	require.Equal(t, []string{"first", "second"}, cfg.AdminAPIKeys)
+}
+
+func TestLoadDeprecations(t *testing.T) {
+	clearEnv(t)
+
+	t.Setenv("API_DEPRECATIONS", "get /api/v1/wallets/{id} 2027-06-30, PUT /api/v1/wallets/deposit")
+
+	cfg, err := Load("test", []string{
+		"-postgres-host", "db", "-postgres-database", "wallets", "-postgres-user", "admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []models.Deprecation{
+		{Method: "GET", Route: "/api/v1/wallets/{id}", Sunset: time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)},
+		{Method: "PUT", Route: "/api/v1/wallets/deposit"},
+	}, cfg.APIDeprecations)
+
+	t.Setenv("API_DEPRECATIONS", "/api/v1/wallets/{id}")
+
+	_, err = Load("test", []string{
+		"-postgres-host", "db", "-postgres-database", "wallets", "-postgres-user", "admin",
+	})
+	require.ErrorIs(t, err, errNotAllowed)
+	require.ErrorContains(t, err, "API_DEPRECATIONS")
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "wallets.yaml", "POSTGRES_HOTS: db\n")
+
+	_, err := Load("test", []string{"-config", path})
+	require.ErrorIs(t, err, errUnknownKey)
+	require.ErrorContains(t, err, "POSTGRES_HOTS")
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	clearEnv(t)
+
+	_, err := Load("test", []string{"-config", filepath.Join(t.TempDir(), "missing.env")})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	clearEnv(t)
+
+	t.Setenv("BIND_ADDRESS", "8080")
+	t.Setenv("POSTGRES_PORT", "70000")
+	t.Setenv("POSTGRES_SSLMODE", "sometimes")
+	t.Setenv("POSTGRES_MAX_CONNS", "2")
+	t.Setenv("POSTGRES_MIN_CONNS", "5")
+	t.Setenv("RECONCILE_INTERVAL", "-1m")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("TLS_CERT_FILE", writeFile(t, "cert.pem", ""))
+
+	_, err := Load("test", nil)
+	require.ErrorIs(t, err, errRequired)
+	require.ErrorIs(t, err, errNotAllowed)
+	require.ErrorIs(t, err, errNegative)
+	require.ErrorIs(t, err, errInconsistent)
+
+	for _, name := range []string{
+		"BIND_ADDRESS", "POSTGRES_PORT", "POSTGRES_SSLMODE", "POSTGRES_MIN_CONNS", "RECONCILE_INTERVAL",
+		"LOG_LEVEL", "TLS_KEY_FILE", "POSTGRES_HOST", "POSTGRES_DATABASE", "POSTGRES_USER",
+	} {
+		require.ErrorContains(t, err, name)
+	}
+}
@@ -0,0 +1,61 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"github.com/iurikman/wallets/internal/models"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//nolint:gochecknoglobals
+var errorCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{err: models.ErrWalletNotFound, code: codes.NotFound},
+	{err: models.ErrBalanceBelowZero, code: codes.FailedPrecondition},
+	{err: models.ErrWalletFrozen, code: codes.FailedPrecondition},
+	{err: models.ErrWalletNotEmpty, code: codes.FailedPrecondition},
+	// The wallet changed since the client read it, so the client should read it again and retry.
+	{err: models.ErrWalletVersionMismatch, code: codes.Aborted},
+	{err: models.ErrWalletIDIsEmpty, code: codes.InvalidArgument},
+	{err: models.ErrAmountIsZero, code: codes.InvalidArgument},
+	{err: models.ErrTransactionTypeIsEmpty, code: codes.InvalidArgument},
+	{err: models.ErrOperationTypeNotAllowed, code: codes.InvalidArgument},
+	{err: models.ErrLimitOutOfRange, code: codes.InvalidArgument},
+	{err: models.ErrInvalidCursor, code: codes.InvalidArgument},
+	{err: models.ErrTransactionNotFound, code: codes.NotFound},
+	{err: models.ErrInvalidAmountRange, code: codes.InvalidArgument},
+	{err: models.ErrInvalidTimeRange, code: codes.InvalidArgument},
+	{err: models.ErrReconciliationNotRun, code: codes.NotFound},
+	{err: models.ErrBatchSizeOutOfRange, code: codes.InvalidArgument},
+	{err: models.ErrTargetWalletIDIsEmpty, code: codes.InvalidArgument},
+	{err: models.ErrTransferToSameWallet, code: codes.InvalidArgument},
+	{err: models.ErrUnexpectedTargetWallet, code: codes.InvalidArgument},
+	{err: models.ErrIdempotencyKeyTooLong, code: codes.InvalidArgument},
+	{err: models.ErrDuplicateIdempotencyKey, code: codes.InvalidArgument},
+	{err: models.ErrIdempotencyKeyReused, code: codes.AlreadyExists},
+	{err: models.ErrReasonTooLong, code: codes.InvalidArgument},
+	{err: models.ErrExternalRefTooLong, code: codes.InvalidArgument},
+	{err: models.ErrExternalRefTaken, code: codes.AlreadyExists},
+	{err: models.ErrTooManyLabels, code: codes.InvalidArgument},
+	{err: models.ErrInvalidLabel, code: codes.InvalidArgument},
+	{err: models.ErrMetadataTooLarge, code: codes.InvalidArgument},
+	{err: models.ErrMetadataNotObject, code: codes.InvalidArgument},
+}
+
+// toStatus maps errors returned by the service to gRPC statuses. Errors without a mapping are
+// logged and reported as Internal without exposing their text to the client.
+func toStatus(err error) error {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return status.Error(e.code, e.err.Error())
+		}
+	}
+
+	log.Warnf("grpc request failed: %v", err)
+
+	return status.Error(codes.Internal, "internal server error")
+}
@@ -0,0 +1,35 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{err: fmt.Errorf("s.db.GetWallet() err: %w", models.ErrWalletNotFound), code: codes.NotFound},
+		{err: models.ErrWalletNotEmpty, code: codes.FailedPrecondition},
+		{err: models.ErrWalletVersionMismatch, code: codes.Aborted},
+		{err: models.ErrBatchSizeOutOfRange, code: codes.InvalidArgument},
+		{err: models.ErrIdempotencyKeyReused, code: codes.AlreadyExists},
+		{err: models.ErrExternalRefTaken, code: codes.AlreadyExists},
+		{err: models.ErrMetadataTooLarge, code: codes.InvalidArgument},
+		{err: models.ErrChangeBalanceData, code: codes.Internal},
+		{err: errors.New("connection refused"), code: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			require.Equal(t, tt.code, status.Code(toStatus(tt.err)))
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package grpcapi
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/grpcapi/walletsv1"
+	"github.com/iurikman/wallets/internal/models"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const gracefulShutdownTimeout = 5 * time.Second
+
+type service interface {
+	CreateWallet(ctx context.Context, attrs models.WalletAttributes) (*models.Wallet, error)
+	GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	Deposit(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error)
+	Withdraw(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error)
+	ListTransactions(ctx context.Context, walletID uuid.UUID, params models.HistoryParams) (*models.TransactionsPage, error)
+}
+
+type ServerConfig struct {
+	BindAddress string
+	// ShutdownTimeout is the time given to in-flight calls on shutdown. Zero means 5 seconds.
+	ShutdownTimeout time.Duration
+}
+
+type Server struct {
+	walletsv1.UnimplementedWalletServiceServer
+
+	serverConfig ServerConfig
+	service      service
+	server       *grpc.Server
+}
+
+func NewServer(serverConfig ServerConfig, srv service) *Server {
+	s := &Server{
+		serverConfig: serverConfig,
+		service:      srv,
+		server:       grpc.NewServer(grpc.UnaryInterceptor(readYourWrites)),
+	}
+
+	walletsv1.RegisterWalletServiceServer(s.server, s)
+
+	return s
+}
+
+// readYourWritesKey is the metadata key with which a client asks to read from the primary
+// database rather than a read replica.
+const readYourWritesKey = "x-read-your-writes"
+
+func readYourWrites(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	for _, value := range metadata.ValueFromIncomingContext(ctx, readYourWritesKey) {
+		if readYourWrites, err := strconv.ParseBool(value); err == nil && readYourWrites {
+			ctx = models.WithReadYourWrites(ctx)
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.serverConfig.BindAddress)
+	if err != nil {
+		return fmt.Errorf("net.Listen(...) err: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		stopped := make(chan struct{})
+
+		go func() {
+			s.server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(cmp.Or(s.serverConfig.ShutdownTimeout, gracefulShutdownTimeout)):
+			log.Warn("failed to stop grpc server gracefully, forcing stop")
+			s.server.Stop()
+		}
+	}()
+
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("s.server.Serve(listener) err: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) CreateWallet(
+	ctx context.Context,
+	_ *walletsv1.CreateWalletRequest,
+) (*walletsv1.CreateWalletResponse, error) {
+	wallet, err := s.service.CreateWallet(ctx, models.WalletAttributes{})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &walletsv1.CreateWalletResponse{Wallet: walletToProto(wallet)}, nil
+}
+
+func (s *Server) GetWallet(ctx context.Context, req *walletsv1.GetWalletRequest) (*walletsv1.GetWalletResponse, error) {
+	walletID, err := parseUUID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.service.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &walletsv1.GetWalletResponse{Wallet: walletToProto(wallet)}, nil
+}
+
+func (s *Server) Deposit(ctx context.Context, req *walletsv1.DepositRequest) (*walletsv1.DepositResponse, error) {
+	transaction, err := newTransaction(req.GetWalletId(), req.GetAmount(), models.OperationDeposit)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.Deposit(ctx, transaction)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &walletsv1.DepositResponse{
+		Transaction:  transactionResultToProto(*result),
+		BalanceAfter: result.BalanceAfter,
+		Pending:      result.Pending,
+	}, nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *walletsv1.WithdrawRequest) (*walletsv1.WithdrawResponse, error) {
+	transaction, err := newTransaction(req.GetWalletId(), req.GetAmount(), models.OperationWithdraw)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.Withdraw(ctx, transaction)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &walletsv1.WithdrawResponse{
+		Transaction:  transactionResultToProto(*result),
+		BalanceAfter: result.BalanceAfter,
+	}, nil
+}
+
+func (s *Server) ListTransactions(
+	ctx context.Context,
+	req *walletsv1.ListTransactionsRequest,
+) (*walletsv1.ListTransactionsResponse, error) {
+	walletID, err := parseUUID("wallet_id", req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+
+	params := models.HistoryParams{Limit: int(req.GetPageSize())}
+
+	if req.GetPageToken() != "" {
+		if params.After, err = models.ParseTransactionCursor(req.GetPageToken()); err != nil {
+			return nil, toStatus(err)
+		}
+	}
+
+	page, err := s.service.ListTransactions(ctx, walletID, params)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &walletsv1.ListTransactionsResponse{
+		Transactions: make([]*walletsv1.Transaction, 0, len(page.Transactions)),
+	}
+
+	for _, transaction := range page.Transactions {
+		resp.Transactions = append(resp.Transactions, transactionResultToProto(transaction))
+	}
+
+	if page.NextCursor != nil {
+		resp.NextPageToken = page.NextCursor.Encode()
+	}
+
+	return resp, nil
+}
+
+func newTransaction(walletID string, amount float64, operationType string) (models.Transaction, error) {
+	id, err := parseUUID("wallet_id", walletID)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	transaction := models.Transaction{
+		WalletID:      id,
+		Amount:        amount,
+		OperationType: operationType,
+	}
+
+	if err := transaction.Validate(); err != nil {
+		return models.Transaction{}, toStatus(err)
+	}
+
+	return transaction, nil
+}
+
+func parseUUID(field, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid %s: %v", field, err)
+	}
+
+	return id, nil
+}
+
+func walletToProto(wallet *models.Wallet) *walletsv1.Wallet {
+	return &walletsv1.Wallet{
+		Id:        wallet.ID.String(),
+		Balance:   wallet.Balance,
+		CreatedAt: timestamppb.New(wallet.CreatedAt),
+		UpdatedAt: timestamppb.New(wallet.UpdatedAt),
+	}
+}
+
+func transactionResultToProto(transaction models.TransactionResult) *walletsv1.Transaction {
+	return &walletsv1.Transaction{
+		Id:              transaction.TransactionID.String(),
+		WalletId:        transaction.WalletID.String(),
+		Amount:          transaction.Amount,
+		TransactionType: transaction.OperationType,
+		ExecutedAt:      timestamppb.New(transaction.ExecutedAt),
+		BalanceAfter:    transaction.BalanceAfter,
+	}
+}
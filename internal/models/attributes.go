@@ -0,0 +1,116 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxExternalRefLength = 255
+	MaxLabels            = 20
+	MaxLabelLength       = 64
+	MaxMetadataBytes     = 4096
+)
+
+// WalletAttributes are data of the client attached to a wallet. Nil fields are left unchanged
+// when a wallet is updated.
+type WalletAttributes struct {
+	// ExternalRef identifies the wallet in the systems of the client, e.g. by an account ID. No
+	// two wallets have the same reference. An empty reference removes it.
+	ExternalRef *string `json:"externalRef"`
+	// Labels group wallets, e.g. merchant or promo. They replace the labels of the wallet.
+	Labels *[]string `json:"labels"`
+	// Metadata is a JSON object, such as a display name, that replaces the metadata of the wallet.
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+func (a WalletAttributes) Validate() error {
+	if a.ExternalRef != nil && len(*a.ExternalRef) > MaxExternalRefLength {
+		return ErrExternalRefTooLong
+	}
+
+	if a.Labels != nil {
+		if len(*a.Labels) > MaxLabels {
+			return ErrTooManyLabels
+		}
+
+		for _, label := range *a.Labels {
+			if !validLabel(label) {
+				return ErrInvalidLabel
+			}
+		}
+	}
+
+	if a.Metadata != nil {
+		if len(a.Metadata) > MaxMetadataBytes {
+			return ErrMetadataTooLarge
+		}
+
+		if !json.Valid(a.Metadata) || !bytes.HasPrefix(bytes.TrimSpace(a.Metadata), []byte("{")) {
+			return ErrMetadataNotObject
+		}
+	}
+
+	return nil
+}
+
+// validLabel reports whether label is made of lower-case letters, digits, dashes and underscores.
+func validLabel(label string) bool {
+	if label == "" || len(label) > MaxLabelLength {
+		return false
+	}
+
+	for _, c := range label {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WalletFilter narrows a wallets search. Nil fields are not applied.
+type WalletFilter struct {
+	Label       *string
+	ExternalRef *string
+}
+
+// WalletCursor points to the last wallet of a page of wallets, which are ordered by creation.
+type WalletCursor struct {
+	CreatedAt time.Time
+	WalletID  uuid.UUID
+}
+
+type WalletsParams struct {
+	Limit int
+	After *WalletCursor
+}
+
+type WalletsPage struct {
+	Wallets    []Wallet
+	NextCursor *WalletCursor
+}
+
+func (p WalletsParams) Validate() error {
+	return HistoryParams{Limit: p.Limit}.Validate()
+}
+
+func (p WalletsParams) LimitOrDefault() int {
+	return HistoryParams{Limit: p.Limit}.LimitOrDefault()
+}
+
+func (c WalletCursor) Encode() string {
+	return encodeCursor(c.CreatedAt, c.WalletID)
+}
+
+func ParseWalletCursor(encoded string) (*WalletCursor, error) {
+	createdAt, id, err := parseCursor(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletCursor{CreatedAt: createdAt, WalletID: id}, nil
+}
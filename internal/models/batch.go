@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// OperationTransfer moves Amount from WalletID to TargetWalletID. It is recorded in history as
+	// a WITHDRAW of the source wallet and a DEPOSIT of the target wallet.
+	OperationTransfer = "TRANSFER"
+
+	MaxBatchSize            = 5000
+	MaxIdempotencyKeyLength = 255
+)
+
+//nolint:gochecknoglobals
+var allowedBatchOperationTypes = map[string]struct{}{
+	OperationDeposit:  {},
+	OperationWithdraw: {},
+	OperationTransfer: {},
+}
+
+type BatchOperation struct {
+	// IdempotencyKey makes retries of the operation return its first result instead of executing
+	// it again. Optional.
+	IdempotencyKey string    `json:"idempotencyKey,omitempty"`
+	OperationType  string    `json:"transactionType"`
+	WalletID       uuid.UUID `json:"walletId"`
+	TargetWalletID uuid.UUID `json:"targetWalletId"`
+	Amount         float64   `json:"amount"`
+}
+
+func (o BatchOperation) Validate() error {
+	if o.WalletID == uuid.Nil {
+		return ErrWalletIDIsEmpty
+	}
+
+	if _, ok := allowedBatchOperationTypes[o.OperationType]; !ok {
+		return ErrOperationTypeNotAllowed
+	}
+
+	if o.Amount <= 0 {
+		return ErrAmountIsZero
+	}
+
+	if o.OperationType != OperationTransfer && o.TargetWalletID != uuid.Nil {
+		return ErrUnexpectedTargetWallet
+	}
+
+	if o.OperationType == OperationTransfer {
+		if o.TargetWalletID == uuid.Nil {
+			return ErrTargetWalletIDIsEmpty
+		}
+
+		if o.TargetWalletID == o.WalletID {
+			return ErrTransferToSameWallet
+		}
+	}
+
+	if len(o.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return ErrIdempotencyKeyTooLong
+	}
+
+	return nil
+}
+
+// Batch is a list of operations executed together. An atomic batch is applied all-or-nothing,
+// otherwise every operation succeeds or fails on its own.
+type Batch struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+func (b Batch) Validate() error {
+	if len(b.Operations) == 0 || len(b.Operations) > MaxBatchSize {
+		return ErrBatchSizeOutOfRange
+	}
+
+	keys := make(map[string]struct{}, len(b.Operations))
+
+	for i, op := range b.Operations {
+		if err := op.Validate(); err != nil {
+			return &BatchItemError{Index: i, Err: err}
+		}
+
+		if op.IdempotencyKey == "" {
+			continue
+		}
+
+		if _, ok := keys[op.IdempotencyKey]; ok {
+			return &BatchItemError{Index: i, Err: ErrDuplicateIdempotencyKey}
+		}
+
+		keys[op.IdempotencyKey] = struct{}{}
+	}
+
+	return nil
+}
+
+// BatchItemError is the error of a single operation of a batch.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchResult is the outcome of a single operation of a batch. Err is set when the operation failed.
+type BatchResult struct {
+	Index int
+	// Replayed is set when the operation was executed before with the same idempotency key.
+	Replayed     bool
+	Transactions []TransactionResult
+	Err          error
+}
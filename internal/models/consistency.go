@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"slices"
+)
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites marks reads made with the returned context as needing the latest writes, so
+// they are not served by a read replica, which may lag behind the primary database.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWrites reports whether ctx was marked by WithReadYourWrites.
+func ReadYourWrites(ctx context.Context) bool {
+	readYourWrites, _ := ctx.Value(readYourWritesKey{}).(bool)
+
+	return readYourWrites
+}
+
+type expectedVersionsKey struct{}
+
+// WithExpectedVersions makes changes of a wallet made with the returned context conditional: they
+// fail with ErrWalletVersionMismatch unless the wallet is at one of versions. No versions means
+// that no version matches.
+func WithExpectedVersions(ctx context.Context, versions []int64) context.Context {
+	return context.WithValue(ctx, expectedVersionsKey{}, slices.Clip(versions))
+}
+
+// CheckVersion returns ErrWalletVersionMismatch when ctx was made by WithExpectedVersions and
+// version is not one of the expected versions.
+func CheckVersion(ctx context.Context, version int64) error {
+	versions, ok := ctx.Value(expectedVersionsKey{}).([]int64)
+	if ok && !slices.Contains(versions, version) {
+		return ErrWalletVersionMismatch
+	}
+
+	return nil
+}
+
+// HasExpectedVersions reports whether ctx was made by WithExpectedVersions.
+func HasExpectedVersions(ctx context.Context) bool {
+	_, ok := ctx.Value(expectedVersionsKey{}).([]int64)
+
+	return ok
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Deprecation announces to clients that an API route is going away.
+type Deprecation struct {
+	// Method and Route name the route, e.g. GET and /api/v1/wallets/{id}.
+	Method string
+	Route  string
+	// Sunset is when the route stops being served. Zero means not decided yet.
+	Sunset time.Time
+}
@@ -10,4 +10,28 @@ var (
 	ErrTransactionTypeIsEmpty  = errors.New("transaction type is empty")
 	ErrChangeBalanceData       = errors.New("change balance data is wrong")
 	ErrOperationTypeNotAllowed = errors.New("operation type not allowed")
+	ErrLimitOutOfRange         = errors.New("limit is out of range")
+	ErrInvalidCursor           = errors.New("cursor is invalid")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrInvalidAmountRange      = errors.New("minimum amount is greater than maximum amount")
+	ErrInvalidTimeRange        = errors.New("time range start is not before its end")
+	ErrWalletFrozen            = errors.New("wallet is frozen")
+	ErrReconciliationNotRun    = errors.New("reconciliation has not run yet")
+	ErrBatchSizeOutOfRange     = errors.New("number of batch operations is out of range")
+	ErrTargetWalletIDIsEmpty   = errors.New("target wallet ID is empty")
+	ErrTransferToSameWallet    = errors.New("transfer source and target wallets are the same")
+	ErrUnexpectedTargetWallet  = errors.New("only transfers have a target wallet")
+	ErrIdempotencyKeyTooLong   = errors.New("idempotency key is too long")
+	ErrDuplicateIdempotencyKey = errors.New("idempotency key is used more than once in the batch")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was already used for a different operation")
+	ErrDuplicateWalletID       = errors.New("wallet ID is used more than once")
+	ErrWalletNotEmpty          = errors.New("wallet balance is not zero")
+	ErrReasonTooLong           = errors.New("reason is too long")
+	ErrWalletVersionMismatch   = errors.New("wallet was changed since the given version")
+	ErrExternalRefTooLong      = errors.New("external reference is too long")
+	ErrExternalRefTaken        = errors.New("external reference belongs to another wallet")
+	ErrTooManyLabels           = errors.New("wallet has too many labels")
+	ErrInvalidLabel            = errors.New("label must be 1 to 64 lower-case letters, digits, dashes or underscores")
+	ErrMetadataTooLarge        = errors.New("metadata is too large")
+	ErrMetadataNotObject       = errors.New("metadata must be a JSON object")
 )
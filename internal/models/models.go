@@ -1,6 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +18,18 @@ type Wallet struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Deleted   bool
+	Frozen    bool
+	// Hot wallets queue deposits as pending credits instead of locking the wallet for each one.
+	Hot bool
+	// PendingBalance is the sum of the pending credits of a hot wallet. It is not spendable yet.
+	PendingBalance float64
+	// Version is incremented by every change of the wallet. Pending credits do not change it
+	// until they are applied.
+	Version int64
+	// ExternalRef, Labels and Metadata are data of the client, see WalletAttributes.
+	ExternalRef string
+	Labels      []string
+	Metadata    json.RawMessage
 }
 
 type Transaction struct {
@@ -20,6 +38,44 @@ type Transaction struct {
 	Amount        float64   `json:"amount"`
 	OperationType string    `json:"transactionType"`
 	ExecutedAt    time.Time `json:"executedAt"`
+	// Reason is a free-form explanation recorded with the transaction, e.g. by an operator.
+	Reason string `json:"reason,omitempty"`
+}
+
+// TransactionResult is a persisted transaction together with the wallet balance right after it.
+type TransactionResult struct {
+	Transaction
+	BalanceAfter float64 `json:"balanceAfter"`
+	// Pending marks a deposit to a hot wallet that was accepted but not applied yet. Its
+	// BalanceAfter is unknown and ExecutedAt is the time it was accepted.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// chainTimeLayout matches what a Postgres timestamp column keeps of a time: the wall clock at
+// microsecond precision, without a time zone.
+const chainTimeLayout = "2006-01-02T15:04:05.000000"
+
+// ChainHash links t to the wallet's history by hashing prev, the hash of the wallet's preceding
+// transaction, together with the transaction contents. prev is empty for the first link.
+func (t TransactionResult) ChainHash(prev []byte) []byte {
+	h := sha256.New()
+
+	h.Write(prev)
+	fmt.Fprintf(h, "\n%s\n%s\n%s\n%s\n%s\n%s",
+		t.TransactionID,
+		t.WalletID,
+		t.OperationType,
+		strconv.FormatFloat(t.Amount, 'f', -1, 64),
+		t.ExecutedAt.Truncate(time.Microsecond).Format(chainTimeLayout),
+		strconv.FormatFloat(t.BalanceAfter, 'f', -1, 64),
+	)
+
+	// Appended only when set, so hashes of transactions written before reasons existed still hold.
+	if t.Reason != "" {
+		fmt.Fprintf(h, "\n%s", t.Reason)
+	}
+
+	return h.Sum(nil)
 }
 
 func (t Transaction) Validate() error {
@@ -39,11 +95,212 @@ func (t Transaction) Validate() error {
 		return ErrTransactionTypeIsEmpty
 	}
 
+	if len(t.Reason) > MaxReasonLength {
+		return ErrReasonTooLong
+	}
+
 	return nil
 }
 
+const MaxReasonLength = 500
+
+const (
+	OperationDeposit  = "DEPOSIT"
+	OperationWithdraw = "WITHDRAW"
+	// OperationOpeningBalance is the first entry of a wallet imported with a non-zero balance.
+	OperationOpeningBalance = "OPENING_BALANCE"
+)
+
 //nolint:gochecknoglobals
 var allowedOperationTypes = map[string]struct{}{
-	"DEPOSIT":  {},
-	"WITHDRAW": {},
+	OperationDeposit:  {},
+	OperationWithdraw: {},
+}
+
+// historyOperationTypes are the operation types found in transaction history.
+//
+//nolint:gochecknoglobals
+var historyOperationTypes = map[string]struct{}{
+	OperationDeposit:        {},
+	OperationWithdraw:       {},
+	OperationOpeningBalance: {},
+}
+
+type TransactionCursor struct {
+	ExecutedAt    time.Time
+	TransactionID uuid.UUID
+}
+
+// TransactionFilter narrows a transactions search. Nil fields are not applied.
+type TransactionFilter struct {
+	WalletID      *uuid.UUID
+	OperationType *string
+	MinAmount     *float64
+	MaxAmount     *float64
+	From          *time.Time
+	To            *time.Time
+}
+
+func (f TransactionFilter) Validate() error {
+	if f.OperationType != nil {
+		if _, ok := historyOperationTypes[*f.OperationType]; !ok {
+			return ErrOperationTypeNotAllowed
+		}
+	}
+
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		return ErrInvalidAmountRange
+	}
+
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return ErrInvalidTimeRange
+	}
+
+	return nil
+}
+
+type HistoryParams struct {
+	Limit int
+	After *TransactionCursor
+}
+
+type TransactionsPage struct {
+	Transactions []TransactionResult
+	NextCursor   *TransactionCursor
+}
+
+// StatementPeriod is the half-open interval [From, To) covered by a wallet statement.
+type StatementPeriod struct {
+	From time.Time
+	To   time.Time
+}
+
+func (p StatementPeriod) Validate() error {
+	if !p.From.Before(p.To) {
+		return ErrInvalidTimeRange
+	}
+
+	return nil
+}
+
+// StatementWriter receives a wallet statement record by record, so statements of any size can be
+// produced without holding the history in memory.
+type StatementWriter interface {
+	Opening(at time.Time, balance float64) error
+	Entry(transaction TransactionResult) error
+	Closing(at time.Time, balance float64) error
+}
+
+// BalanceAt is the balance of a wallet at a point in time.
+type BalanceAt struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Balance  float64   `json:"balance"`
+	At       time.Time `json:"at"`
+}
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
+)
+
+func (p HistoryParams) Validate() error {
+	if p.Limit < 0 || p.Limit > MaxHistoryLimit {
+		return ErrLimitOutOfRange
+	}
+
+	return nil
+}
+
+func (p HistoryParams) LimitOrDefault() int {
+	if p.Limit == 0 {
+		return DefaultHistoryLimit
+	}
+
+	return p.Limit
+}
+
+func (c TransactionCursor) Encode() string {
+	return encodeCursor(c.ExecutedAt, c.TransactionID)
+}
+
+func ParseTransactionCursor(encoded string) (*TransactionCursor, error) {
+	executedAt, id, err := parseCursor(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionCursor{ExecutedAt: executedAt, TransactionID: id}, nil
+}
+
+// encodeCursor encodes the position of a row in a listing ordered by time and ID.
+func encodeCursor(at time.Time, id uuid.UUID) string {
+	raw := at.UTC().Format(time.RFC3339Nano) + cursorSeparator + id.String()
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseCursor(encoded string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	rawAt, rawID, ok := strings.Cut(string(raw), cursorSeparator)
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, rawAt)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	return at, id, nil
+}
+
+const cursorSeparator = "|"
+
+// BalanceDrift is a wallet whose stored balance differs from the balance computed from its history.
+type BalanceDrift struct {
+	WalletID        uuid.UUID `json:"walletId"`
+	StoredBalance   float64   `json:"storedBalance"`
+	ComputedBalance float64   `json:"computedBalance"`
+	Delta           float64   `json:"delta"`
+}
+
+type ReconciliationReport struct {
+	StartedAt      time.Time      `json:"startedAt"`
+	FinishedAt     time.Time      `json:"finishedAt"`
+	WalletsChecked int            `json:"walletsChecked"`
+	Drifts         []BalanceDrift `json:"drifts"`
+	FrozenWallets  int            `json:"frozenWallets"`
+	Error          string         `json:"error,omitempty"`
+}
+
+const (
+	ChainBreakMissingHash  = "transaction has no hash"
+	ChainBreakPrevMismatch = "previous hash does not match the preceding transaction"
+	ChainBreakHashMismatch = "hash does not match the transaction contents"
+)
+
+// ChainVerification is the result of walking the hash chain of a wallet's history.
+type ChainVerification struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Valid    bool      `json:"valid"`
+	// Verified is the number of links checked before the walk finished or hit BrokenLink.
+	Verified int `json:"verified"`
+	// Unchained is the number of transactions written before the hash chain was introduced.
+	Unchained  int         `json:"unchained"`
+	BrokenLink *ChainBreak `json:"brokenLink,omitempty"`
+}
+
+type ChainBreak struct {
+	TransactionID uuid.UUID `json:"transactionId"`
+	ExecutedAt    time.Time `json:"executedAt"`
+	Reason        string    `json:"reason"`
 }
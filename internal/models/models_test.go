@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainHash(t *testing.T) {
+	executedAt := time.Date(2026, 10, 19, 12, 30, 0, 123456789, time.FixedZone("UTC+3", 3*60*60))
+
+	transaction := TransactionResult{
+		Transaction: Transaction{
+			TransactionID: uuid.New(),
+			WalletID:      uuid.New(),
+			Amount:        0.1,
+			OperationType: OperationDeposit,
+			ExecutedAt:    executedAt,
+		},
+		BalanceAfter: 100.1,
+	}
+
+	prev := transaction.ChainHash(nil)
+	hash := transaction.ChainHash(prev)
+
+	require.Len(t, hash, 32)
+	require.NotEqual(t, prev, hash)
+
+	// Postgres returns the wall clock in UTC, truncated to microseconds.
+	stored := transaction
+	stored.ExecutedAt = time.Date(2026, 10, 19, 12, 30, 0, 123456000, time.UTC)
+	require.Equal(t, hash, stored.ChainHash(prev))
+
+	edited := transaction
+	edited.Amount = 0.2
+	require.NotEqual(t, hash, edited.ChainHash(prev))
+}
+
+func TestBatchValidate(t *testing.T) {
+	walletID := uuid.New()
+	deposit := BatchOperation{OperationType: OperationDeposit, WalletID: walletID, Amount: 1}
+
+	tests := []struct {
+		name  string
+		batch Batch
+		err   error
+		index int
+	}{
+		{
+			name:  "valid",
+			batch: Batch{Operations: []BatchOperation{deposit, deposit}},
+		},
+		{
+			name:  "empty",
+			batch: Batch{},
+			err:   ErrBatchSizeOutOfRange,
+			index: -1,
+		},
+		{
+			name: "transfer without target",
+			batch: Batch{Operations: []BatchOperation{
+				deposit,
+				{OperationType: OperationTransfer, WalletID: walletID, Amount: 1},
+			}},
+			err:   ErrTargetWalletIDIsEmpty,
+			index: 1,
+		},
+		{
+			name: "transfer to the same wallet",
+			batch: Batch{Operations: []BatchOperation{
+				{OperationType: OperationTransfer, WalletID: walletID, TargetWalletID: walletID, Amount: 1},
+			}},
+			err: ErrTransferToSameWallet,
+		},
+		{
+			name: "deposit with a target wallet",
+			batch: Batch{Operations: []BatchOperation{
+				{OperationType: OperationDeposit, WalletID: walletID, TargetWalletID: uuid.New(), Amount: 1},
+			}},
+			err: ErrUnexpectedTargetWallet,
+		},
+		{
+			name: "duplicate idempotency key",
+			batch: Batch{Operations: []BatchOperation{
+				{IdempotencyKey: "a", OperationType: OperationDeposit, WalletID: walletID, Amount: 1},
+				{IdempotencyKey: "a", OperationType: OperationWithdraw, WalletID: walletID, Amount: 1},
+			}},
+			err:   ErrDuplicateIdempotencyKey,
+			index: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.batch.Validate()
+			if tt.err == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, tt.err)
+
+			var itemErr *BatchItemError
+			if tt.index < 0 {
+				require.False(t, errors.As(err, &itemErr))
+
+				return
+			}
+
+			require.ErrorAs(t, err, &itemErr)
+			require.Equal(t, tt.index, itemErr.Index)
+		})
+	}
+}
+
+func TestWalletAttributesValidate(t *testing.T) {
+	ref := func(s string) *string { return &s }
+	labels := func(l ...string) *[]string { return &l }
+
+	tests := []struct {
+		name  string
+		attrs WalletAttributes
+		err   error
+	}{
+		{name: "empty"},
+		{
+			name:  "valid",
+			attrs: WalletAttributes{ExternalRef: ref("acc-1"), Labels: labels("merchant", "promo_2"), Metadata: []byte(`{"name": "Shop"}`)},
+		},
+		{name: "long external ref", attrs: WalletAttributes{ExternalRef: ref(strings.Repeat("a", 256))}, err: ErrExternalRefTooLong},
+		{name: "too many labels", attrs: WalletAttributes{Labels: labels(strings.Split(strings.Repeat("a,", 20)+"a", ",")...)}, err: ErrTooManyLabels},
+		{name: "upper-case label", attrs: WalletAttributes{Labels: labels("Merchant")}, err: ErrInvalidLabel},
+		{name: "empty label", attrs: WalletAttributes{Labels: labels("")}, err: ErrInvalidLabel},
+		{name: "large metadata", attrs: WalletAttributes{Metadata: []byte(`{"a": "` + strings.Repeat("a", 4096) + `"}`)}, err: ErrMetadataTooLarge},
+		{name: "metadata array", attrs: WalletAttributes{Metadata: []byte(`[1]`)}, err: ErrMetadataNotObject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.attrs.Validate(), tt.err)
+		})
+	}
+}
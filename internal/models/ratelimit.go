@@ -0,0 +1,80 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit allows Burst requests at once and Rate requests per second on average. A zero
+// RateLimit allows everything.
+//
+// Buckets follow the generic cell rate algorithm: instead of a token count, a bucket keeps the
+// time at which it would be full again, its theoretical arrival time (TAT). Every request moves
+// the TAT one emission interval, 1/Rate, further; a request is rejected when that would put the
+// TAT more than Burst intervals ahead of now.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateDecision is the outcome of taking a request from a bucket.
+type RateDecision struct {
+	Allowed bool
+	// Limit is the burst of the bucket and Remaining how many requests it allows right now.
+	Limit     int
+	Remaining int
+	// RetryAfter is the time until the next request is allowed, zero when it is allowed now.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full.
+	Reset time.Duration
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Interval is the emission interval: the time in which the bucket refills by one request.
+func (l RateLimit) Interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+// Take applies a request made at now to a bucket with the given TAT, the zero time for a new
+// bucket, and returns the decision and the new TAT.
+func (l RateLimit) Take(tat, now time.Time) (RateDecision, time.Time) {
+	next := later(tat, now).Add(l.Interval())
+	allowed := next.Sub(now) <= time.Duration(l.Burst)*l.Interval()
+
+	if !allowed {
+		return l.Decision(tat, now, false), tat
+	}
+
+	return l.Decision(next, now, true), next
+}
+
+// Decision describes a bucket whose TAT is tat after a request made at now was allowed or not.
+func (l RateLimit) Decision(tat, now time.Time, allowed bool) RateDecision {
+	interval := l.Interval()
+	ahead := max(tat.Sub(now), 0)
+
+	decision := RateDecision{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: max(0, int(math.Floor(float64(time.Duration(l.Burst)*interval-ahead)/float64(interval)))),
+		Reset:     ahead,
+	}
+
+	if decision.Remaining == 0 {
+		// The next request is allowed once the TAT is at most Burst-1 intervals ahead.
+		decision.RetryAfter = max(ahead-time.Duration(l.Burst-1)*interval, 0)
+	}
+
+	return decision
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitTake(t *testing.T) {
+	limit := RateLimit{Rate: 2, Burst: 3}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	var (
+		tat      time.Time
+		decision RateDecision
+	)
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		decision, tat = limit.Take(tat, now)
+		require.True(t, decision.Allowed)
+		require.Equal(t, 3, decision.Limit)
+		require.Equal(t, remaining, decision.Remaining)
+	}
+
+	require.Equal(t, 1500*time.Millisecond, decision.Reset)
+	require.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+
+	rejected, rejectedTAT := limit.Take(tat, now)
+	require.False(t, rejected.Allowed)
+	require.Equal(t, tat, rejectedTAT)
+	require.Equal(t, 500*time.Millisecond, rejected.RetryAfter)
+
+	decision, tat = limit.Take(tat, now.Add(rejected.RetryAfter))
+	require.True(t, decision.Allowed)
+	require.Zero(t, decision.Remaining)
+
+	decision, _ = limit.Take(tat, now.Add(time.Hour))
+	require.True(t, decision.Allowed)
+	require.Equal(t, 2, decision.Remaining)
+	require.Zero(t, decision.RetryAfter)
+}
+
+func TestRateLimitEnabled(t *testing.T) {
+	require.False(t, RateLimit{}.Enabled())
+	require.False(t, RateLimit{Rate: 1}.Enabled())
+	require.True(t, RateLimit{Rate: 1, Burst: 1}.Enabled())
+}
@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/iurikman/wallets/internal/models"
+)
+
+type reconciler interface {
+	Reconcile(ctx context.Context) (*models.ReconciliationReport, error)
+	LastReport() (*models.ReconciliationReport, error)
+}
+
+func (s *Server) getReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.reconciler.LastReport()
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, report)
+}
+
+func (s *Server) runReconciliation(w http.ResponseWriter, r *http.Request) {
+	report, err := s.reconciler.Reconcile(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, report)
+}
+
+func (s *Server) verifyChain(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	verification, err := s.service.VerifyChain(r.Context(), walletID)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, verification)
+}
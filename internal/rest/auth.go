@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	ScopeAdmin = "admin"
+
+	principalIDLength = 12
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	ID     string
+	Scopes []string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalKey struct{}
+
+func principalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+
+	return principal, ok
+}
+
+// authenticate resolves the API key from the Authorization header into a Principal. Requests
+// without credentials pass through anonymously; requests with unknown credentials are rejected.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		key, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			writeUnauthorized(w, r, "authorization header must use the Bearer scheme")
+
+			return
+		}
+
+		principal, ok := s.lookupAPIKey(key)
+		if !ok {
+			writeUnauthorized(w, r, "invalid API key")
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+func (s *Server) lookupAPIKey(key string) (Principal, bool) {
+	for _, adminKey := range s.serverConfig.AdminAPIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1 {
+			return Principal{ID: apiKeyID(key), Scopes: []string{ScopeAdmin}}, true
+		}
+	}
+
+	return Principal{}, false
+}
+
+// apiKeyID derives a stable identifier from an API key that is safe to log.
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return "key:" + hex.EncodeToString(sum[:])[:principalIDLength]
+}
+
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := principalFromContext(r.Context())
+			if !ok {
+				writeUnauthorized(w, r, "authentication required")
+
+				return
+			}
+
+			if !principal.HasScope(scope) {
+				writeErrorResponse(w, r, http.StatusForbidden, &HTTPError{
+					Code:    ErrCodeForbidden,
+					Message: "missing scope " + scope,
+				})
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="wallets"`)
+	writeErrorResponse(w, r, http.StatusUnauthorized, &HTTPError{
+		Code:    ErrCodeUnauthorized,
+		Message: message,
+	})
+}
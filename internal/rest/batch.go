@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+const (
+	batchStatusOK     = "ok"
+	batchStatusFailed = "failed"
+)
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+type BatchItemResult struct {
+	Index        int                        `json:"index"`
+	Status       string                     `json:"status"`
+	Replayed     bool                       `json:"replayed,omitempty"`
+	Transactions []models.TransactionResult `json:"transactions,omitempty"`
+	Error        *HTTPError                 `json:"error,omitempty"`
+}
+
+func (s *Server) executeBatch(w http.ResponseWriter, r *http.Request) {
+	var batch models.Batch
+
+	if !decodeJSON(w, r, &batch) {
+		return
+	}
+
+	if !s.allowWallets(w, r, debitedWallets(batch)...) {
+		return
+	}
+
+	results, err := s.service.ExecuteBatch(r.Context(), batch)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	resp := BatchResponse{Results: make([]BatchItemResult, 0, len(results))}
+
+	for _, result := range results {
+		item := BatchItemResult{
+			Index:        result.Index,
+			Status:       batchStatusOK,
+			Replayed:     result.Replayed,
+			Transactions: result.Transactions,
+		}
+
+		if result.Err != nil {
+			item.Status = batchStatusFailed
+
+			if _, item.Error = mapError(result.Err); item.Error == nil {
+				item.Error = internalError()
+			}
+		}
+
+		resp.Results = append(resp.Results, item)
+	}
+
+	writeOkResponse(w, http.StatusOK, resp)
+}
+
+// debitedWallets lists the wallet of every withdrawal and transfer of a batch, once per operation.
+// Every debit takes a token of the wallet rate limit, as it would when sent on its own, so that a
+// batch cannot be used to get around the limit on withdrawals.
+func debitedWallets(batch models.Batch) []uuid.UUID {
+	walletIDs := make([]uuid.UUID, 0)
+
+	for _, op := range batch.Operations {
+		if op.OperationType != models.OperationDeposit {
+			walletIDs = append(walletIDs, op.WalletID)
+		}
+	}
+
+	return walletIDs
+}
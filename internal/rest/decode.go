@@ -0,0 +1,184 @@
+package rest
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonMediaType       = "application/json"
+	defaultMaxBodyBytes = 1 << 20
+)
+
+var errTrailingData = errors.New("request body has data after the JSON value")
+
+// limitBody rejects request bodies that are not JSON and stops reading them after MaxBodyBytes.
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != jsonMediaType {
+			writeErrorResponse(w, r, http.StatusUnsupportedMediaType, &HTTPError{
+				Code:    ErrCodeUnsupportedMedia,
+				Message: "request body must be " + jsonMediaType,
+			})
+
+			return
+		}
+
+		maxBytes := cmp.Or(s.serverConfig.MaxBodyBytes, defaultMaxBodyBytes)
+		if r.ContentLength > maxBytes {
+			writeBodyTooLarge(w, r, maxBytes)
+
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptJSON rejects requests whose Accept header rules out JSON responses.
+func acceptJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !accepts(r, jsonMediaType) {
+			writeErrorResponse(w, r, http.StatusNotAcceptable, &HTTPError{
+				Code:    ErrCodeNotAcceptable,
+				Message: "responses are " + jsonMediaType,
+			})
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// accepts reports whether the Accept header of r allows mediaType. No header allows anything.
+func accepts(r *http.Request, mediaType string) bool {
+	values := r.Header.Values("Accept")
+	if len(values) == 0 {
+		return true
+	}
+
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	for _, value := range values {
+		for _, mediaRange := range strings.Split(value, ",") {
+			accepted, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+
+			if accepted == mediaType || accepted == mainType+"/*" || accepted == "*/*" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// decodeJSON decodes the request body, a single JSON value without fields unknown to dest, into
+// dest. It writes the error response and returns false when the body cannot be decoded.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dest any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(dest)
+	if err == nil {
+		if _, tokenErr := decoder.Token(); !errors.Is(tokenErr, io.EOF) {
+			err = cmp.Or(maxBytesError(tokenErr), errTrailingData)
+		}
+	}
+
+	if err != nil {
+		writeDecodeError(w, r, err)
+
+		return false
+	}
+
+	return true
+}
+
+// decodeValid decodes the request body into dest like decodeJSON and validates it.
+func decodeValid(w http.ResponseWriter, r *http.Request, dest interface{ Validate() error }) bool {
+	if !decodeJSON(w, r, dest) {
+		return false
+	}
+
+	if err := dest.Validate(); err != nil {
+		writeError(w, r, err)
+
+		return false
+	}
+
+	return true
+}
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeBodyTooLarge(w, r, maxBytesErr.Limit)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		writeValidationError(w, r, "invalid request body", FieldError{
+			Field:   typeErr.Field,
+			Message: "must be a JSON " + jsonTypeName(typeErr.Type),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields.
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+
+		writeValidationError(w, r, "invalid request body", FieldError{Field: field, Message: "is not a known field"})
+	case errors.Is(err, io.EOF):
+		writeInvalidRequest(w, r, "request body is empty")
+	case errors.Is(err, errTrailingData):
+		writeInvalidRequest(w, r, "request body must be a single JSON value")
+	default:
+		writeInvalidRequest(w, r, "request body is malformed")
+	}
+}
+
+func writeInvalidRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeErrorResponse(w, r, http.StatusBadRequest, &HTTPError{
+		Code:    ErrCodeInvalidRequest,
+		Message: message,
+	})
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	writeErrorResponse(w, r, http.StatusRequestEntityTooLarge, &HTTPError{
+		Code:    ErrCodeBodyTooLarge,
+		Message: "request body is larger than " + strconv.FormatInt(maxBytes, 10) + " bytes",
+	})
+}
+
+// maxBytesError returns err when it is due to a body over the size limit, and nil otherwise.
+func maxBytesError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeValid(t *testing.T) {
+	const valid = `{"walletId": "6b3ce9a4-1c1d-4d5e-9f4a-2b8c2f0e7f11", "amount": 10, "transactionType": "DEPOSIT"}`
+
+	s := &Server{serverConfig: ServerConfig{MaxBodyBytes: 256}}
+	handler := s.limitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var transaction models.Transaction
+
+		if decodeValid(w, r, &transaction) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	for _, tc := range []struct {
+		name        string
+		body        string
+		contentType string
+		chunked     bool
+		statusCode  int
+		code        string
+		field       string
+	}{
+		{name: "valid", body: valid, statusCode: http.StatusNoContent},
+		{name: "charset", body: valid, contentType: "application/json; charset=utf-8", statusCode: http.StatusNoContent},
+		{name: "trailing whitespace", body: valid + "\n", statusCode: http.StatusNoContent},
+		{name: "empty", statusCode: http.StatusBadRequest, code: ErrCodeInvalidRequest},
+		{name: "malformed", body: `{"amount": `, statusCode: http.StatusBadRequest, code: ErrCodeInvalidRequest},
+		{name: "two values", body: valid + valid, statusCode: http.StatusBadRequest, code: ErrCodeInvalidRequest},
+		{
+			name: "unknown field", body: `{"amount": 10, "currency": "EUR"}`,
+			statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "currency",
+		},
+		{
+			name: "wrong type", body: `{"amount": "10"}`,
+			statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "amount",
+		},
+		{
+			name: "invalid", body: `{"walletId": "6b3ce9a4-1c1d-4d5e-9f4a-2b8c2f0e7f11", "transactionType": "DEPOSIT"}`,
+			statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "amount",
+		},
+		{
+			name: "not json", body: valid, contentType: "text/plain",
+			statusCode: http.StatusUnsupportedMediaType, code: ErrCodeUnsupportedMedia,
+		},
+		{
+			name: "too large", body: `{"reason": "` + strings.Repeat("x", 256) + `"}`,
+			statusCode: http.StatusRequestEntityTooLarge, code: ErrCodeBodyTooLarge,
+		},
+		{
+			name: "too large without length", body: `{"reason": "` + strings.Repeat("x", 256) + `"}`, chunked: true,
+			statusCode: http.StatusRequestEntityTooLarge, code: ErrCodeBodyTooLarge,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tc.body)
+			if tc.chunked {
+				body = io.MultiReader(body)
+			}
+
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/wallets/deposit", body)
+			if tc.body != "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tc.statusCode, w.Code, w.Body.String())
+
+			if tc.code == "" {
+				return
+			}
+
+			var resp HTTPResponse
+
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Equal(t, tc.code, resp.Error.Code)
+
+			if tc.field != "" {
+				require.Len(t, resp.Error.Details, 1)
+				require.Equal(t, tc.field, resp.Error.Details[0].Field)
+			}
+		})
+	}
+}
+
+func TestAcceptJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		accept  []string
+		allowed bool
+	}{
+		{name: "absent", allowed: true},
+		{name: "json", accept: []string{"application/json"}, allowed: true},
+		{name: "any", accept: []string{"*/*"}, allowed: true},
+		{name: "any application", accept: []string{"application/*;q=0.5"}, allowed: true},
+		{name: "list", accept: []string{"text/html, application/json;q=0.9"}, allowed: true},
+		{name: "several headers", accept: []string{"text/html", "application/json"}, allowed: true},
+		{name: "csv", accept: []string{"text/csv"}, allowed: false},
+		{name: "refused", accept: []string{"application/json;q=0, */*;q=0"}, allowed: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/", nil)
+			for _, value := range tc.accept {
+				r.Header.Add("Accept", value)
+			}
+
+			w := httptest.NewRecorder()
+			acceptJSON(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})).ServeHTTP(w, r)
+
+			if tc.allowed {
+				require.Equal(t, http.StatusNoContent, w.Code)
+			} else {
+				require.Equal(t, http.StatusNotAcceptable, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/iurikman/wallets/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// Error codes are stable machine readable identifiers returned in HTTPError.Code.
+const (
+	ErrCodeInvalidRequest      = "INVALID_REQUEST"
+	ErrCodeValidationFailed    = "VALIDATION_FAILED"
+	ErrCodeWalletNotFound      = "WALLET_NOT_FOUND"
+	ErrCodeTransactionNotFound = "TRANSACTION_NOT_FOUND"
+	ErrCodeInsufficientFunds   = "INSUFFICIENT_FUNDS"
+	ErrCodeWalletFrozen        = "WALLET_FROZEN"
+	ErrCodeReportNotFound      = "REPORT_NOT_FOUND"
+	ErrCodeIdempotencyKeyReuse = "IDEMPOTENCY_KEY_REUSED"
+	ErrCodeVersionMismatch     = "VERSION_MISMATCH"
+	ErrCodeExternalRefTaken    = "EXTERNAL_REF_TAKEN"
+	ErrCodeRouteNotFound       = "ROUTE_NOT_FOUND"
+	ErrCodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	ErrCodeNotAcceptable       = "NOT_ACCEPTABLE"
+	ErrCodeBodyTooLarge        = "BODY_TOO_LARGE"
+	ErrCodeUnsupportedMedia    = "UNSUPPORTED_MEDIA_TYPE"
+	ErrCodeUnauthorized        = "UNAUTHORIZED"
+	ErrCodeForbidden           = "FORBIDDEN"
+	ErrCodeInternal            = "INTERNAL_ERROR"
+)
+
+type HTTPError struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type errorMapping struct {
+	err        error
+	statusCode int
+	code       string
+	field      string
+}
+
+// errorMappings is the single place that decides how errors from the models package are
+// presented to clients. Errors that are not listed here are reported as internal errors.
+//
+//nolint:gochecknoglobals
+var errorMappings = []errorMapping{
+	{err: models.ErrWalletNotFound, statusCode: http.StatusNotFound, code: ErrCodeWalletNotFound},
+	{err: models.ErrBalanceBelowZero, statusCode: http.StatusBadRequest, code: ErrCodeInsufficientFunds},
+	{err: models.ErrWalletFrozen, statusCode: http.StatusConflict, code: ErrCodeWalletFrozen},
+	{err: models.ErrWalletIDIsEmpty, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "walletId"},
+	{err: models.ErrAmountIsZero, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "amount"},
+	{err: models.ErrTransactionTypeIsEmpty, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "transactionType"},
+	{err: models.ErrOperationTypeNotAllowed, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "transactionType"},
+	{err: models.ErrLimitOutOfRange, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "limit"},
+	{err: models.ErrInvalidCursor, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "cursor"},
+	{err: models.ErrTransactionNotFound, statusCode: http.StatusNotFound, code: ErrCodeTransactionNotFound},
+	{err: models.ErrInvalidAmountRange, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "minAmount"},
+	{err: models.ErrInvalidTimeRange, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "from"},
+	{err: models.ErrReconciliationNotRun, statusCode: http.StatusNotFound, code: ErrCodeReportNotFound},
+	{err: models.ErrBatchSizeOutOfRange, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "operations"},
+	{err: models.ErrTargetWalletIDIsEmpty, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "targetWalletId"},
+	{err: models.ErrTransferToSameWallet, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "targetWalletId"},
+	{err: models.ErrUnexpectedTargetWallet, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "targetWalletId"},
+	{err: models.ErrIdempotencyKeyTooLong, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "idempotencyKey"},
+	{err: models.ErrDuplicateIdempotencyKey, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "idempotencyKey"},
+	{err: models.ErrIdempotencyKeyReused, statusCode: http.StatusConflict, code: ErrCodeIdempotencyKeyReuse},
+	{err: models.ErrReasonTooLong, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "reason"},
+	{err: models.ErrWalletVersionMismatch, statusCode: http.StatusPreconditionFailed, code: ErrCodeVersionMismatch},
+	{err: models.ErrExternalRefTooLong, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "externalRef"},
+	{err: models.ErrExternalRefTaken, statusCode: http.StatusConflict, code: ErrCodeExternalRefTaken, field: "externalRef"},
+	{err: models.ErrTooManyLabels, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "labels"},
+	{err: models.ErrInvalidLabel, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "labels"},
+	{err: models.ErrMetadataTooLarge, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "metadata"},
+	{err: models.ErrMetadataNotObject, statusCode: http.StatusBadRequest, code: ErrCodeValidationFailed, field: "metadata"},
+	{err: models.ErrChangeBalanceData, statusCode: http.StatusInternalServerError, code: ErrCodeInternal},
+}
+
+// writeError maps err to a status code and error object and writes it to the client.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, httpErr := mapError(err)
+	if httpErr == nil {
+		log.Warnf("request %s %s failed: %v", r.Method, r.URL.Path, err)
+
+		httpErr = internalError()
+	}
+
+	writeErrorResponse(w, r, statusCode, httpErr)
+}
+
+// mapError returns the status code and error object presented to clients for err. The error
+// object is nil when err must be reported as an internal error. Fields of errors of a batch
+// operation are prefixed with the position of the operation.
+func mapError(err error) (int, *HTTPError) {
+	for _, m := range errorMappings {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+
+		if m.statusCode >= http.StatusInternalServerError {
+			break
+		}
+
+		httpErr := &HTTPError{Code: m.code, Message: m.err.Error()}
+		field := m.field
+
+		var itemErr *models.BatchItemError
+		if errors.As(err, &itemErr) {
+			field = fmt.Sprintf("operations[%d]", itemErr.Index)
+			if m.field != "" && m.field != "operations" {
+				field += "." + m.field
+			}
+		}
+
+		if field != "" {
+			httpErr.Details = []FieldError{{Field: field, Message: m.err.Error()}}
+		}
+
+		return m.statusCode, httpErr
+	}
+
+	return http.StatusInternalServerError, nil
+}
+
+func internalError() *HTTPError {
+	return &HTTPError{
+		Code:    ErrCodeInternal,
+		Message: "internal server error",
+	}
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, message string, details ...FieldError) {
+	writeErrorResponse(w, r, http.StatusBadRequest, &HTTPError{
+		Code:    ErrCodeValidationFailed,
+		Message: message,
+		Details: details,
+	})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeErrorResponse(w, r, http.StatusNotFound, &HTTPError{
+		Code:    ErrCodeRouteNotFound,
+		Message: "route not found",
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeErrorResponse(w, r, http.StatusMethodNotAllowed, &HTTPError{
+		Code:    ErrCodeMethodNotAllowed,
+		Message: "method not allowed",
+	})
+}
+
+// requestID echoes the request ID assigned by middleware.RequestID back to the client.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+
+		next.ServeHTTP(w, r)
+	})
+}
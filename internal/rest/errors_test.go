@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		field  string
+	}{
+		{
+			name:   "wrapped wallet not found",
+			err:    fmt.Errorf("s.db.GetWallet(ctx, id) err: %w", models.ErrWalletNotFound),
+			status: http.StatusNotFound,
+			code:   ErrCodeWalletNotFound,
+		},
+		{
+			name:   "insufficient funds",
+			err:    models.ErrBalanceBelowZero,
+			status: http.StatusBadRequest,
+			code:   ErrCodeInsufficientFunds,
+		},
+		{
+			name:   "frozen wallet conflicts",
+			err:    fmt.Errorf("s.db.Deposit(ctx, transaction) err: %w", models.ErrWalletFrozen),
+			status: http.StatusConflict,
+			code:   ErrCodeWalletFrozen,
+		},
+		{
+			name:   "version mismatch fails the precondition",
+			err:    fmt.Errorf("s.db.Withdraw() err: %w", models.ErrWalletVersionMismatch),
+			status: http.StatusPreconditionFailed,
+			code:   ErrCodeVersionMismatch,
+		},
+		{
+			name:   "validation error has field details",
+			err:    models.ErrAmountIsZero,
+			status: http.StatusBadRequest,
+			code:   ErrCodeValidationFailed,
+			field:  "amount",
+		},
+		{
+			name:   "batch operation error field has its position",
+			err:    fmt.Errorf("batch.Validate() err: %w", &models.BatchItemError{Index: 2, Err: models.ErrAmountIsZero}),
+			status: http.StatusBadRequest,
+			code:   ErrCodeValidationFailed,
+			field:  "operations[2].amount",
+		},
+		{
+			name:   "unknown error is internal",
+			err:    errors.New("connection reset by peer"),
+			status: http.StatusInternalServerError,
+			code:   ErrCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+
+			middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, tt.err)
+			})).ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+
+			var resp HTTPResponse
+
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			require.NotNil(t, resp.Error)
+			require.Equal(t, tt.code, resp.Error.Code)
+			require.NotEmpty(t, resp.Error.RequestID)
+			require.NotContains(t, resp.Error.Message, "connection reset")
+
+			if tt.field != "" {
+				require.Len(t, resp.Error.Details, 1)
+				require.Equal(t, tt.field, resp.Error.Details[0].Field)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iurikman/wallets/internal/models"
+)
+
+// walletETag is the strong entity tag of a wallet version.
+func walletETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch returns the request context, made conditional on the wallet versions in the If-Match
+// header when it is present. "*" matches any wallet that exists. Weak tags and tags that are not
+// wallet versions never match, as RFC 9110 requires strong comparison for If-Match.
+func ifMatch(r *http.Request) context.Context {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return r.Context()
+	}
+
+	versions := make([]int64, 0)
+
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" {
+				return r.Context()
+			}
+
+			unquoted, ok := strings.CutPrefix(tag, `"`)
+			if !ok {
+				continue
+			}
+
+			unquoted, ok = strings.CutSuffix(unquoted, `"`)
+			if !ok {
+				continue
+			}
+
+			if version, err := strconv.ParseInt(unquoted, 10, 64); err == nil {
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	return models.WithExpectedVersions(r.Context(), versions)
+}
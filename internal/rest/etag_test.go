@@ -0,0 +1,75 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIfMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		header      []string
+		conditional bool
+		matches     []int64
+	}{
+		{name: "absent", conditional: false},
+		{name: "any", header: []string{"*"}, conditional: false},
+		{name: "one version", header: []string{walletETag(7)}, conditional: true, matches: []int64{7}},
+		{name: "list", header: []string{`"3", "5"`, `"8"`}, conditional: true, matches: []int64{3, 5, 8}},
+		{name: "weak tag", header: []string{`W/"7"`}, conditional: true},
+		{name: "not a version", header: []string{`"abc"`, "7"}, conditional: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/wallets/deposit", nil)
+			for _, value := range tc.header {
+				r.Header.Add("If-Match", value)
+			}
+
+			ctx := ifMatch(r)
+			require.Equal(t, tc.conditional, models.HasExpectedVersions(ctx))
+
+			for version := range int64(10) {
+				if tc.conditional && !slices.Contains(tc.matches, version) {
+					require.ErrorIs(t, models.CheckVersion(ctx, version), models.ErrWalletVersionMismatch)
+				} else {
+					require.NoError(t, models.CheckVersion(ctx, version))
+				}
+			}
+		})
+	}
+}
+
+// primaryService records whether wallets are read from the primary database.
+type primaryService struct {
+	service
+	readYourWrites bool
+}
+
+func (s *primaryService) GetWallet(ctx context.Context, _ uuid.UUID) (*models.Wallet, error) {
+	s.readYourWrites = models.ReadYourWrites(ctx)
+
+	return &models.Wallet{Version: 3}, nil
+}
+
+func TestWalletETagIsReadFromThePrimary(t *testing.T) {
+	svc := &primaryService{}
+
+	srv, err := NewServer(ServerConfig{}, svc, nil)
+	require.NoError(t, err)
+
+	srv.configRouter()
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+uuid.NewString(), nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, walletETag(3), w.Header().Get("ETag"))
+	require.True(t, svc.readYourWrites)
+}
@@ -3,140 +3,162 @@ package rest
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
+	"reflect"
+	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 	"github.com/google/uuid"
 	"github.com/iurikman/wallets/internal/models"
 	log "github.com/sirupsen/logrus"
 )
 
 type service interface {
-	CreateWallet(context context.Context) (*models.Wallet, error)
+	CreateWallet(ctx context.Context, attrs models.WalletAttributes) (*models.Wallet, error)
+	UpdateWallet(ctx context.Context, id uuid.UUID, attrs models.WalletAttributes) (*models.Wallet, error)
+	ListWallets(ctx context.Context, filter models.WalletFilter, params models.WalletsParams) (*models.WalletsPage, error)
 	GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
-	Deposit(ctx context.Context, transaction models.Transaction) error
-	Withdraw(ctx context.Context, transaction models.Transaction) error
+	Deposit(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error)
+	Withdraw(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error)
+	GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*models.BalanceAt, error)
+	GetTransaction(ctx context.Context, id uuid.UUID) (*models.TransactionResult, error)
+	ListTransactions(ctx context.Context, walletID uuid.UUID, params models.HistoryParams) (*models.TransactionsPage, error)
+	WriteStatement(ctx context.Context, walletID uuid.UUID, period models.StatementPeriod, w models.StatementWriter) error
+	VerifyChain(ctx context.Context, walletID uuid.UUID) (*models.ChainVerification, error)
+	ExecuteBatch(ctx context.Context, batch models.Batch) ([]models.BatchResult, error)
+	Transfer(ctx context.Context, transfer models.BatchOperation) ([]models.TransactionResult, error)
+	SearchTransactions(
+		ctx context.Context,
+		filter models.TransactionFilter,
+		params models.HistoryParams,
+	) (*models.TransactionsPage, error)
 }
 
 type HTTPResponse struct {
-	Data  any    `json:"data"`
-	Error string `json:"error"`
+	Data  any        `json:"data"`
+	Error *HTTPError `json:"error"`
 }
 
 func (s *Server) createWallet(w http.ResponseWriter, r *http.Request) {
-	createdWallet, err := s.service.CreateWallet(r.Context())
+	var attrs models.WalletAttributes
+
+	// The attributes are optional, and so is the body.
+	if r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody {
+		if requestVersion(r).legacy && !dropLegacyWalletFields(w, r) {
+			return
+		}
+
+		if !decodeValid(w, r, &attrs) {
+			return
+		}
+	}
+
+	createdWallet, err := s.service.CreateWallet(r.Context(), attrs)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
-		log.Warnf("failed to create new wallet: %v", err)
+		writeError(w, r, err)
 
 		return
 	}
 
+	w.Header().Set("ETag", walletETag(createdWallet.Version))
 	writeOkResponse(w, http.StatusCreated, createdWallet)
 }
 
 func (s *Server) getWallet(w http.ResponseWriter, r *http.Request) {
-	walletID := chi.URLParam(r, "id")
-	if walletID == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Missing 'id' parameter")
-
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
 		return
 	}
 
-	walletIDParsed, err := uuid.Parse(walletID)
+	// The ETag is used in If-Match right away, so it comes from the primary: a lagging replica would
+	// hand out a version that is already stale.
+	wallet, err := s.service.GetWallet(models.WithReadYourWrites(r.Context()), walletID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, err)
+
+		return
+	}
+
+	w.Header().Set("ETag", walletETag(wallet.Version))
+	writeOkResponse(w, http.StatusOK, wallet)
+}
 
+func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
 		return
 	}
 
-	wallet, err := s.service.GetWallet(r.Context(), walletIDParsed)
+	query := queryParser{values: r.URL.Query()}
 
-	switch {
-	case errors.Is(err, models.ErrWalletNotFound):
-		writeErrorResponse(w, http.StatusNotFound, err.Error())
+	at := time.Now()
+	if t := query.time("at"); t != nil {
+		at = *t
+	}
+
+	if len(query.errors) > 0 {
+		writeValidationError(w, r, "invalid query parameters", query.errors...)
 
 		return
-	case err != nil:
-		writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
+	}
+
+	balance, err := s.service.GetBalanceAt(r.Context(), walletID, at)
+	if err != nil {
+		writeError(w, r, err)
 
 		return
 	}
 
-	writeOkResponse(w, http.StatusOK, wallet)
+	writeOkResponse(w, http.StatusOK, balance)
 }
 
 func (s *Server) deposit(w http.ResponseWriter, r *http.Request) {
 	var transaction models.Transaction
 
-	w.Header().Set("Content-Type", "application/json")
-
-	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
-
+	if !decodeValid(w, r, &transaction) {
 		return
 	}
 
-	if err := transaction.Validate(); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+	result, err := s.service.Deposit(ifMatch(r), transaction)
+	if err != nil {
+		writeError(w, r, err)
 
 		return
 	}
 
-	err := s.service.Deposit(r.Context(), transaction)
-
-	switch {
-	case errors.Is(err, models.ErrWalletNotFound):
-		writeErrorResponse(w, http.StatusNotFound, err.Error())
-
-		return
-	case err != nil:
-		writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
-		log.Warnf("failed to deposit transaction: %v", err)
+	// A deposit to a hot wallet is queued, and the transaction is found once it is applied, so
+	// there is no transaction to point to yet.
+	if result.Pending {
+		writeOkResponse(w, http.StatusAccepted, result)
 
 		return
 	}
 
-	writeOkResponse(w, http.StatusOK, nil)
+	w.Header().Set("Location", transactionLocation(r, result.Transaction))
+	writeOkResponse(w, http.StatusOK, result)
 }
 
 func (s *Server) withdraw(w http.ResponseWriter, r *http.Request) {
 	var transaction models.Transaction
 
-	w.Header().Set("Content-Type", "application/json")
-
-	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
-
+	if !decodeValid(w, r, &transaction) {
 		return
 	}
 
-	if err := transaction.Validate(); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
-
+	if !s.allowWallets(w, r, transaction.WalletID) {
 		return
 	}
 
-	err := s.service.Withdraw(r.Context(), transaction)
-
-	switch {
-	case errors.Is(err, models.ErrWalletNotFound):
-		writeErrorResponse(w, http.StatusNotFound, err.Error())
-
-		return
-	case errors.Is(err, models.ErrBalanceBelowZero):
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
-
-		return
-	case err != nil:
-		writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
-		log.Warnf("failed to withdraw transaction: %v", err)
+	result, err := s.service.Withdraw(ifMatch(r), transaction)
+	if err != nil {
+		writeError(w, r, err)
 
 		return
 	}
 
-	writeOkResponse(w, http.StatusOK, nil)
+	w.Header().Set("Location", transactionLocation(r, result.Transaction))
+	writeOkResponse(w, http.StatusOK, result)
 }
 
 func writeOkResponse(w http.ResponseWriter, statusCode int, respData any) {
@@ -148,11 +170,47 @@ func writeOkResponse(w http.ResponseWriter, statusCode int, respData any) {
 	}
 }
 
-func writeErrorResponse(w http.ResponseWriter, statusCode int, description string) {
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, httpErr *HTTPError) {
+	httpErr.RequestID = middleware.GetReqID(r.Context())
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	if err := json.NewEncoder(w).Encode(HTTPResponse{Error: description}); err != nil {
-		log.Warnf("json.NewEncoder(w).Encode(HTTPResponse{Error: description}) err: %s", err)
+	if err := json.NewEncoder(w).Encode(HTTPResponse{Error: httpErr}); err != nil {
+		log.Warnf("json.NewEncoder(w).Encode(HTTPResponse{Error: httpErr}) err: %s", err)
+	}
+}
+
+// transactionLocation is the URL of a transaction under its wallet in the API version of the
+// request, where the clients of the wallet can read it.
+func transactionLocation(r *http.Request, transaction models.Transaction) string {
+	return requestVersion(r).prefix() + "/wallets/" + transaction.WalletID.String() + "/transactions/" + transaction.TransactionID.String()
+}
+
+func walletIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidationError(w, r, "invalid wallet id", FieldError{Field: "id", Message: "must be a valid UUID"})
+
+		return uuid.Nil, false
+	}
+
+	return walletID, true
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
 	}
 }
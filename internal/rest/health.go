@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	healthCheckTimeout = 2 * time.Second
+
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// HealthCheck reports whether a dependency of the service is usable.
+type HealthCheck func(ctx context.Context) error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+type healthCheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]healthCheckResult `json:"checks,omitempty"`
+}
+
+// AddReadinessCheck registers a check evaluated by /readyz. It must be called before Start.
+func (s *Server) AddReadinessCheck(name string, check HealthCheck) {
+	s.readinessChecks = append(s.readinessChecks, namedHealthCheck{name: name, check: check})
+}
+
+func (s *Server) liveness(w http.ResponseWriter, _ *http.Request) {
+	writeHealthReport(w, http.StatusOK, healthReport{Status: healthStatusOK})
+}
+
+func (s *Server) readiness(w http.ResponseWriter, r *http.Request) {
+	report := healthReport{
+		Status: healthStatusOK,
+		Checks: make(map[string]healthCheckResult, len(s.readinessChecks)+1),
+	}
+
+	if s.shuttingDown.Load() {
+		report.Status = healthStatusFail
+		report.Checks["shutdown"] = healthCheckResult{Status: healthStatusFail, Error: "server is shutting down"}
+	}
+
+	for _, c := range s.readinessChecks {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		err := c.check(ctx)
+
+		cancel()
+
+		if err != nil {
+			report.Status = healthStatusFail
+			report.Checks[c.name] = healthCheckResult{Status: healthStatusFail, Error: err.Error()}
+
+			continue
+		}
+
+		report.Checks[c.name] = healthCheckResult{Status: healthStatusOK}
+	}
+
+	if report.Status != healthStatusOK {
+		writeHealthReport(w, http.StatusServiceUnavailable, report)
+
+		return
+	}
+
+	writeHealthReport(w, http.StatusOK, report)
+}
+
+func writeHealthReport(w http.ResponseWriter, statusCode int, report healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Warnf("json.NewEncoder(w).Encode(report) err: %v", err)
+	}
+}
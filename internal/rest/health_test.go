@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	srv, err := NewServer(ServerConfig{BindAddress: address, DrainDelay: time.Second}, nil, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- srv.Start(ctx)
+	}()
+
+	readyz := func() int {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+address+"/readyz", nil)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0
+		}
+
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	require.Eventually(t, func() bool { return readyz() == http.StatusOK }, time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	// The server keeps serving during the drain, with readiness failing.
+	require.Eventually(t, func() bool { return readyz() == http.StatusServiceUnavailable }, 500*time.Millisecond, 10*time.Millisecond)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down after the drain")
+	}
+
+	require.Zero(t, readyz())
+}
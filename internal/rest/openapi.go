@@ -0,0 +1,193 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+type openAPI struct {
+	doc    *openapi3.T
+	router routers.Router
+	// raw is the document served to clients.
+	raw []byte
+}
+
+// loadOpenAPI loads the OpenAPI document of every API version. openapi.json describes the first
+// version; the documents of later versions are derived from it.
+func loadOpenAPI() (map[string]*openAPI, error) {
+	specs := make(map[string]*openAPI, len(apiVersions))
+
+	for i, version := range apiVersions {
+		spec, err := loadVersionOpenAPI(version, i == 0)
+		if err != nil {
+			return nil, fmt.Errorf("loadVersionOpenAPI(%s) err: %w", version.name, err)
+		}
+
+		specs[version.name] = spec
+	}
+
+	return specs, nil
+}
+
+func loadVersionOpenAPI(version apiVersion, first bool) (*openAPI, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("openapi3.NewLoader().LoadFromData(...) err: %w", err)
+	}
+
+	raw := openAPISpec
+
+	if !first {
+		deriveOpenAPI(doc, version)
+
+		if raw, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return nil, fmt.Errorf("json.MarshalIndent(doc) err: %w", err)
+		}
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("doc.Validate(...) err: %w", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("gorillamux.NewRouter(doc) err: %w", err)
+	}
+
+	return &openAPI{
+		doc:    doc,
+		router: router,
+		raw:    raw,
+	}, nil
+}
+
+// deriveOpenAPI turns the document of the first API version into that of version: its routes
+// move under the prefix of version, legacy routes are dropped unless version serves them, and
+// the adapters of version are applied to the schemas.
+func deriveOpenAPI(doc *openapi3.T, version apiVersion) {
+	first := apiVersions[0].prefix() + "/"
+	paths := openapi3.NewPaths()
+
+	for path, item := range doc.Paths.Map() {
+		route, ok := strings.CutPrefix(path, first)
+		if !ok {
+			paths.Set(path, item)
+
+			continue
+		}
+
+		if !version.legacy {
+			for method := range item.Operations() {
+				if _, legacy := legacySuccessors[method+" "+path]; legacy {
+					item.SetOperation(method, nil)
+				}
+			}
+
+			if len(item.Operations()) == 0 {
+				continue
+			}
+		}
+
+		paths.Set(version.prefix()+"/"+route, item)
+	}
+
+	doc.Paths = paths
+	doc.Info.Version = strings.TrimPrefix(version.name, "v") + ".0.0"
+
+	if version.adaptSpec != nil {
+		version.adaptSpec(doc)
+	}
+}
+
+func (o *openAPI) serve(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(o.raw); err != nil {
+		log.Warnf("w.Write(o.raw) err: %v", err)
+	}
+}
+
+// validateRequest rejects requests that do not match the OpenAPI document. Requests to routes
+// that are not described in the document are passed through to the router unchanged.
+func (o *openAPI) validateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := o.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:         false,
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeBodyTooLarge(w, r, maxBytesErr.Limit)
+
+				return
+			}
+
+			writeValidationError(w, r, "request does not match the API specification", requestValidationDetails(err)...)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requestValidationDetails(err error) []FieldError {
+	var (
+		reqErr    *openapi3filter.RequestError
+		schemaErr *openapi3.SchemaError
+	)
+
+	field := "body"
+
+	if errors.As(err, &reqErr) && reqErr.Parameter != nil {
+		field = reqErr.Parameter.Name
+	}
+
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			field = strings.Join(pointer, ".")
+		}
+
+		return []FieldError{{Field: field, Message: schemaErr.Reason}}
+	}
+
+	if reqErr != nil {
+		message := reqErr.Reason
+		if message == "" {
+			message = reqErr.Error()
+		}
+
+		return []FieldError{{Field: field, Message: message}}
+	}
+
+	return nil
+}
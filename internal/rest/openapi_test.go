@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	srv, err := NewServer(ServerConfig{}, nil, nil)
+	require.NoError(t, err)
+
+	srv.configRouter()
+
+	err = chi.Walk(srv.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		spec := srv.openAPI[apiVersions[0].name]
+
+		for _, version := range apiVersions {
+			if strings.HasPrefix(route, version.prefix()+"/") {
+				spec = srv.openAPI[version.name]
+			}
+		}
+
+		pathItem := spec.doc.Paths.Find(route)
+		if !assertRoute(t, pathItem != nil, "route %s %s is missing from openapi.json", method, route) {
+			return nil
+		}
+
+		assertRoute(t, pathItem.GetOperation(method) != nil, "operation %s %s is missing from openapi.json", method, route)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestValidateRequest(t *testing.T) {
+	srv, err := NewServer(ServerConfig{}, nil, nil)
+	require.NoError(t, err)
+
+	handler := srv.openAPI["v1"].validateRequest(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{
+			name:   "valid deposit",
+			method: http.MethodPut,
+			path:   "/api/v1/wallets/deposit",
+			body:   `{"walletId":"5f1e1a4c-5a8e-4b8e-9a53-0b7d1d5c7c11","amount":10,"transactionType":"DEPOSIT"}`,
+			status: http.StatusTeapot,
+		},
+		{
+			name:   "unknown transaction type",
+			method: http.MethodPut,
+			path:   "/api/v1/wallets/withdraw",
+			body:   `{"walletId":"5f1e1a4c-5a8e-4b8e-9a53-0b7d1d5c7c11","amount":10,"transactionType":"STEAL"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "negative amount",
+			method: http.MethodPut,
+			path:   "/api/v1/wallets/deposit",
+			body:   `{"walletId":"5f1e1a4c-5a8e-4b8e-9a53-0b7d1d5c7c11","amount":-1,"transactionType":"DEPOSIT"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "body is not an object",
+			method: http.MethodPut,
+			path:   "/api/v1/wallets/deposit",
+			body:   `"bad request"`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "route not in spec",
+			method: http.MethodGet,
+			path:   "/api/v1/unknown",
+			status: http.StatusTeapot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func assertRoute(t *testing.T, ok bool, format string, args ...any) bool {
+	t.Helper()
+
+	if !ok {
+		t.Errorf(format, args...)
+	}
+
+	return ok
+}
@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+// OperationRequest is the body of a deposit to or a withdrawal from the wallet named in the URL.
+type OperationRequest struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// TransferRequest is the body of a transfer from the wallet named in the URL.
+type TransferRequest struct {
+	TargetWalletID uuid.UUID `json:"targetWalletId"`
+	Amount         float64   `json:"amount"`
+	// IdempotencyKey makes retries of the transfer return its first result instead of executing
+	// it again. Optional.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+func (s *Server) createDeposit(w http.ResponseWriter, r *http.Request) {
+	s.createOperation(w, r, models.OperationDeposit, s.service.Deposit)
+}
+
+func (s *Server) createWithdrawal(w http.ResponseWriter, r *http.Request) {
+	s.createOperation(w, r, models.OperationWithdraw, s.service.Withdraw)
+}
+
+// createOperation deposits to or withdraws from the wallet named in the URL with execute and
+// responds with the created transaction.
+func (s *Server) createOperation(
+	w http.ResponseWriter,
+	r *http.Request,
+	operationType string,
+	execute func(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error),
+) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req OperationRequest
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	transaction := models.Transaction{
+		WalletID:      walletID,
+		Amount:        req.Amount,
+		OperationType: operationType,
+		Reason:        req.Reason,
+	}
+
+	if err := transaction.Validate(); err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	if operationType == models.OperationWithdraw && !s.allowWallets(w, r, walletID) {
+		return
+	}
+
+	result, err := execute(ifMatch(r), transaction)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	// A deposit to a hot wallet is queued, and the transaction is found once it is applied, so
+	// there is no transaction to point to yet.
+	if result.Pending {
+		writeOkResponse(w, http.StatusAccepted, result)
+
+		return
+	}
+
+	w.Header().Set("Location", transactionLocation(r, result.Transaction))
+	writeOkResponse(w, http.StatusCreated, result)
+}
+
+func (s *Server) createTransfer(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req TransferRequest
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	transfer := models.BatchOperation{
+		IdempotencyKey: req.IdempotencyKey,
+		OperationType:  models.OperationTransfer,
+		WalletID:       walletID,
+		TargetWalletID: req.TargetWalletID,
+		Amount:         req.Amount,
+	}
+
+	if !s.allowWallets(w, r, walletID) {
+		return
+	}
+
+	transactions, err := s.service.Transfer(ifMatch(r), transfer)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Location", transactionLocation(r, transactions[0].Transaction))
+	writeOkResponse(w, http.StatusCreated, transactions)
+}
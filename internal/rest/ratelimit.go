@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ErrCodeRateLimited = "RATE_LIMITED"
+
+	rateLimitScopeClient = "client"
+	rateLimitScopeWallet = "wallet"
+
+	// memoryRateLimitPruneInterval is how often MemoryRateLimiter forgets buckets that are full.
+	memoryRateLimitPruneInterval = time.Minute
+)
+
+//nolint:gochecknoglobals
+var rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "wallets_http_rate_limited_total",
+	Help: "Number of requests rejected by rate limiting, by the limit they hit.",
+}, []string{"scope"})
+
+// RateLimiter takes requests from token buckets identified by key.
+type RateLimiter interface {
+	Take(ctx context.Context, key string, limit models.RateLimit) (models.RateDecision, error)
+}
+
+// MemoryRateLimiter keeps the buckets in memory, so every replica of the service limits on its own.
+type MemoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]time.Time
+	pruned  time.Time
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{buckets: make(map[string]time.Time)}
+}
+
+func (l *MemoryRateLimiter) Take(_ context.Context, key string, limit models.RateLimit) (models.RateDecision, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.pruned) >= memoryRateLimitPruneInterval {
+		for k, tat := range l.buckets {
+			if tat.Before(now) {
+				delete(l.buckets, k)
+			}
+		}
+
+		l.pruned = now
+	}
+
+	decision, tat := limit.Take(l.buckets[key], now)
+	l.buckets[key] = tat
+
+	return decision, nil
+}
+
+// limitClient limits the requests of every client: the principal of authenticated requests and
+// the remote address of anonymous ones.
+func (s *Server) limitClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.allowRequest(w, r, rateLimitScopeClient, clientKey(r), s.serverConfig.ClientRateLimit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allowWallets limits the withdrawals from every wallet, whoever makes them. Deposits are not
+// limited, as busy wallets receive many. It writes the error response and returns false when
+// one of the wallets is over its limit.
+func (s *Server) allowWallets(w http.ResponseWriter, r *http.Request, walletIDs ...uuid.UUID) bool {
+	for _, walletID := range walletIDs {
+		if !s.allowRequest(w, r, rateLimitScopeWallet, walletID.String(), s.serverConfig.WalletRateLimit) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) allowRequest(w http.ResponseWriter, r *http.Request, scope, key string, limit models.RateLimit) bool {
+	if s.serverConfig.RateLimiter == nil || !limit.Enabled() {
+		return true
+	}
+
+	decision, err := s.serverConfig.RateLimiter.Take(r.Context(), scope+":"+key, limit)
+	if err != nil {
+		// Failing open: an unavailable limiter must not take the API down with it.
+		log.Warnf("rate limiting %s %s failed: %v", scope, key, err)
+
+		return true
+	}
+
+	setRateLimitHeaders(w, decision)
+
+	if decision.Allowed {
+		return true
+	}
+
+	rateLimited.WithLabelValues(scope).Inc()
+
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(decision.RetryAfter))))
+	writeErrorResponse(w, r, http.StatusTooManyRequests, &HTTPError{
+		Code:    ErrCodeRateLimited,
+		Message: "too many requests for this " + scope,
+	})
+
+	return false
+}
+
+// setRateLimitHeaders sets the RateLimit header fields of the IETF httpapi draft. A request that
+// passes both the client and the wallet limit reports the wallet one, which is checked last.
+func setRateLimitHeaders(w http.ResponseWriter, decision models.RateDecision) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := principalFromContext(r.Context()); ok {
+		return principal.ID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
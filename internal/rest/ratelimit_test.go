@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitClient(t *testing.T) {
+	srv, err := NewServer(ServerConfig{
+		AdminAPIKeys:    []string{"admin-key"},
+		RateLimiter:     NewMemoryRateLimiter(),
+		ClientRateLimit: models.RateLimit{Rate: 0.001, Burst: 2},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	handler := srv.authenticate(srv.limitClient(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+
+	send := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+uuid.NewString(), nil)
+		r.RemoteAddr = remoteAddr
+
+		if apiKey != "" {
+			r.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		w := send("10.0.0.1:5000", "")
+		require.Equal(t, http.StatusTeapot, w.Code)
+		require.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		require.Equal(t, remaining, w.Header().Get("RateLimit-Remaining"))
+	}
+
+	w := send("10.0.0.1:5001", "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var resp HTTPResponse
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, ErrCodeRateLimited, resp.Error.Code)
+
+	// Other clients have buckets of their own.
+	require.Equal(t, http.StatusTeapot, send("10.0.0.2:5000", "").Code)
+	require.Equal(t, http.StatusTeapot, send("10.0.0.1:5000", "admin-key").Code)
+}
+
+func TestAllowWallets(t *testing.T) {
+	srv, err := NewServer(ServerConfig{
+		RateLimiter:     NewMemoryRateLimiter(),
+		WalletRateLimit: models.RateLimit{Rate: 0.001, Burst: 1},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	first, second := uuid.New(), uuid.New()
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/wallets/withdraw", nil)
+
+	require.True(t, srv.allowWallets(httptest.NewRecorder(), r, first))
+
+	w := httptest.NewRecorder()
+	require.False(t, srv.allowWallets(w, r, second, first))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	require.False(t, srv.allowWallets(httptest.NewRecorder(), r, second), "the token of second was taken")
+
+	require.Equal(t, []uuid.UUID{first, second, first}, debitedWallets(models.Batch{Operations: []models.BatchOperation{
+		{OperationType: models.OperationWithdraw, WalletID: first},
+		{OperationType: models.OperationDeposit, WalletID: uuid.New()},
+		{OperationType: models.OperationTransfer, WalletID: second, TargetWalletID: first},
+		{OperationType: models.OperationWithdraw, WalletID: first},
+	}}))
+}
+
+func TestBatchWithdrawalsTakeATokenEach(t *testing.T) {
+	srv, err := NewServer(ServerConfig{
+		RateLimiter:     NewMemoryRateLimiter(),
+		WalletRateLimit: models.RateLimit{Rate: 0.001, Burst: 2},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	srv.configRouter()
+
+	walletID := uuid.New()
+	withdrawal := models.BatchOperation{OperationType: models.OperationWithdraw, WalletID: walletID, Amount: 1}
+
+	body, err := json.Marshal(models.Batch{Operations: []models.BatchOperation{withdrawal, withdrawal, withdrawal}})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/operations/batch", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+
+	// The first two withdrawals used up the tokens of the wallet.
+	r = httptest.NewRequest(http.MethodPut, "/api/v1/wallets/withdraw", nil)
+	require.False(t, srv.allowWallets(httptest.NewRecorder(), r, walletID))
+}
+
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Take(_ context.Context, _ string, _ models.RateLimit) (models.RateDecision, error) {
+	return models.RateDecision{}, context.DeadlineExceeded
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	srv, err := NewServer(ServerConfig{
+		RateLimiter:     failingRateLimiter{},
+		WalletRateLimit: models.RateLimit{Rate: 1, Burst: 1},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/wallets/withdraw", nil)
+	require.True(t, srv.allowWallets(httptest.NewRecorder(), r, uuid.New()))
+}
@@ -1,44 +1,96 @@
 package rest
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
 type ServerConfig struct {
-	BindAddress string
+	BindAddress  string
+	AdminAPIKeys []string
+
+	// ReadTimeout, WriteTimeout and IdleTimeout are those of http.Server. Zero means no timeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is the time given to in-flight requests on shutdown. Zero means 5 seconds.
+	ShutdownTimeout time.Duration
+	// DrainDelay is the time readiness fails before shutdown starts, so that load balancers stop
+	// sending requests first. Zero means 5 seconds.
+	DrainDelay time.Duration
+
+	// MaxBodyBytes is the size of the largest request body accepted. Zero means 1 MiB.
+	MaxBodyBytes int64
+
+	// TLSCertFile and TLSKeyFile, when set, make the server serve HTTPS.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// RateLimiter, when set, limits the API requests of every client to ClientRateLimit and the
+	// withdrawals from every wallet to WalletRateLimit. A zero limit is not applied.
+	RateLimiter     RateLimiter
+	ClientRateLimit models.RateLimit
+	WalletRateLimit models.RateLimit
+
+	// Deprecations are announced on the responses of their routes, in addition to the legacy
+	// routes, which are always deprecated.
+	Deprecations []models.Deprecation
 }
 
 const (
 	readHeaderTimeout       = 10 * time.Second
 	maxHeaderBytes          = 1 << 20
 	gracefulShutdownTimeout = 5 * time.Second
+	readinessDrainDelay     = 5 * time.Second
 )
 
 type Server struct {
 	serverConfig ServerConfig
 	service      service
+	reconciler   reconciler
 	router       *chi.Mux
 	server       *http.Server
+	openAPI      map[string]*openAPI
+	deprecations map[string]models.Deprecation
+
+	readinessChecks []namedHealthCheck
+	shuttingDown    atomic.Bool
 }
 
-func NewServer(serverConfig ServerConfig, srv service) (*Server, error) {
+func NewServer(serverConfig ServerConfig, srv service, rec reconciler) (*Server, error) {
 	router := chi.NewRouter()
 
+	spec, err := loadOpenAPI()
+	if err != nil {
+		return nil, fmt.Errorf("loadOpenAPI() err: %w", err)
+	}
+
 	return &Server{
 		serverConfig: serverConfig,
 		service:      srv,
+		reconciler:   rec,
 		router:       router,
+		openAPI:      spec,
+		deprecations: deprecations(serverConfig.Deprecations),
 		server: &http.Server{
 			Addr:              serverConfig.BindAddress,
 			Handler:           router,
 			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       serverConfig.ReadTimeout,
+			WriteTimeout:      serverConfig.WriteTimeout,
+			IdleTimeout:       serverConfig.IdleTimeout,
 			MaxHeaderBytes:    maxHeaderBytes,
 		},
 	}, nil
@@ -49,7 +101,17 @@ func (s *Server) Start(ctx context.Context) error {
 
 	go func() {
 		<-ctx.Done()
-		ctxWithTimeout, cancel := context.WithTimeout(ctx, gracefulShutdownTimeout)
+
+		drainDelay := cmp.Or(s.serverConfig.DrainDelay, readinessDrainDelay)
+
+		s.shuttingDown.Store(true)
+		logrus.Infof("readiness set to failing, draining traffic for %s", drainDelay)
+		time.Sleep(drainDelay)
+
+		ctxWithTimeout, cancel := context.WithTimeout(
+			context.WithoutCancel(ctx),
+			cmp.Or(s.serverConfig.ShutdownTimeout, gracefulShutdownTimeout),
+		)
 
 		defer cancel()
 
@@ -59,7 +121,14 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}()
 
-	err := s.server.ListenAndServe()
+	var err error
+
+	if s.serverConfig.TLSCertFile != "" {
+		err = s.server.ListenAndServeTLS(s.serverConfig.TLSCertFile, s.serverConfig.TLSKeyFile)
+	} else {
+		err = s.server.ListenAndServe()
+	}
+
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("s.server.ListenAndServe() err: %w", err)
 	}
@@ -68,13 +137,83 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) configRouter() {
-	s.router.Route("/api/v1", func(r chi.Router) {
+	s.router.Use(middleware.RequestID, requestID, readYourWrites, s.authenticate)
+
+	s.router.NotFound(notFound)
+	s.router.MethodNotAllowed(methodNotAllowed)
+
+	s.router.Get("/healthz", s.liveness)
+	s.router.Get("/readyz", s.readiness)
+	s.router.Method(http.MethodGet, "/metrics", promhttp.Handler())
+
+	for _, version := range apiVersions {
+		s.router.Route(version.prefix(), func(r chi.Router) {
+			s.apiRoutes(r, version)
+		})
+	}
+}
+
+// apiRoutes serves version of the API. The versions share the handlers.
+func (s *Server) apiRoutes(r chi.Router, version apiVersion) {
+	spec := s.openAPI[version.name]
+
+	r.Use(s.limitClient, s.limitBody, s.deprecate, spec.validateRequest, version.adapt)
+
+	// Statements are written in the format asked for by the client, so only they are not JSON.
+	r.Get("/wallets/{id}/statement", s.getStatement)
+
+	r.Group(func(r chi.Router) {
+		r.Use(acceptJSON)
+
+		r.Get("/openapi.json", spec.serve)
+
 		r.Route("/wallets", func(r chi.Router) {
 			r.Post("/", s.createWallet)
+			r.With(requireScope(ScopeAdmin)).Get("/", s.listWallets)
 			r.Get("/{id}", s.getWallet)
+			r.Patch("/{id}", s.updateWallet)
+			r.Get("/{id}/balance", s.getBalance)
+			r.Get("/{id}/transactions", s.listWalletTransactions)
+			r.Get("/{id}/transactions/{transactionId}", s.getWalletTransaction)
+			r.Post("/{id}/deposits", s.createDeposit)
+			r.Post("/{id}/withdrawals", s.createWithdrawal)
+			r.Post("/{id}/transfers", s.createTransfer)
+
+			if version.legacy {
+				r.Put("/withdraw", s.withdraw)
+				r.Put("/deposit", s.deposit)
+			}
+		})
+
+		r.Post("/operations/batch", s.executeBatch)
+
+		r.Route("/transactions", func(r chi.Router) {
+			r.Use(requireScope(ScopeAdmin))
 
-			r.Put("/withdraw", s.withdraw)
-			r.Put("/deposit", s.deposit)
+			r.Get("/", s.searchTransactions)
+			r.Get("/{id}", s.getTransaction)
 		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(requireScope(ScopeAdmin))
+
+			r.Get("/reconciliation", s.getReconciliationReport)
+			r.Post("/reconciliation", s.runReconciliation)
+			r.Get("/wallets/{id}/chain", s.verifyChain)
+		})
+	})
+}
+
+const readYourWritesHeader = "X-Read-Your-Writes"
+
+// readYourWrites sends the reads of a request to the primary database when the client asks for it
+// with the X-Read-Your-Writes header, e.g. right after creating a wallet.
+func readYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if value, err := strconv.ParseBool(r.Header.Get(readYourWritesHeader)); err == nil && value {
+			r = r.WithContext(models.WithReadYourWrites(r.Context()))
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
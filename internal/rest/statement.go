@@ -0,0 +1,252 @@
+package rest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iurikman/wallets/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	statementFormatCSV   = "csv"
+	statementFormatJSONL = "jsonl"
+
+	// statementFlushEvery bounds how many records are buffered before they are sent to the client.
+	statementFlushEvery = 500
+)
+
+func (s *Server) getStatement(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	query := queryParser{values: r.URL.Query()}
+
+	var period models.StatementPeriod
+
+	if from := query.time("from"); from != nil {
+		period.From = *from
+	}
+
+	if to := query.time("to"); to != nil {
+		period.To = *to
+	}
+
+	format := statementFormatCSV
+	if f := query.string("format"); f != nil {
+		format = *f
+	}
+
+	var encoder statementEncoder
+
+	switch format {
+	case statementFormatCSV:
+		encoder = &csvStatementEncoder{}
+	case statementFormatJSONL:
+		encoder = &jsonlStatementEncoder{}
+	default:
+		query.errors = append(query.errors, FieldError{Field: "format", Message: "must be one of csv, jsonl"})
+	}
+
+	if len(query.errors) > 0 {
+		writeValidationError(w, r, "invalid query parameters", query.errors...)
+
+		return
+	}
+
+	sw := &statementWriter{
+		w:        w,
+		encoder:  encoder,
+		filename: fmt.Sprintf("statement-%s.%s", walletID, format),
+	}
+
+	err := s.service.WriteStatement(r.Context(), walletID, period, sw)
+	if err == nil {
+		err = sw.flush()
+	}
+
+	switch {
+	case err != nil && !sw.started:
+		writeError(w, r, err)
+	case err != nil:
+		// The status line is already sent; a statement without its closing record is incomplete.
+		log.Warnf("failed to stream statement of wallet %s: %v", walletID, err)
+	}
+}
+
+// statementEncoder serializes statement records in one output format.
+type statementEncoder interface {
+	contentType() string
+	begin(w http.ResponseWriter)
+	opening(at time.Time, balance float64) error
+	entry(transaction models.TransactionResult) error
+	closing(at time.Time, balance float64) error
+	flush() error
+}
+
+// statementWriter adapts a statementEncoder to models.StatementWriter. Headers are written lazily
+// with the first record, so errors that happen before the statement starts still get a proper
+// error response.
+type statementWriter struct {
+	w        http.ResponseWriter
+	encoder  statementEncoder
+	filename string
+	started  bool
+	pending  int
+}
+
+func (s *statementWriter) Opening(at time.Time, balance float64) error {
+	s.w.Header().Set("Content-Type", s.encoder.contentType())
+	s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename))
+	s.w.WriteHeader(http.StatusOK)
+	s.encoder.begin(s.w)
+	s.started = true
+
+	return s.encoder.opening(at, balance)
+}
+
+func (s *statementWriter) Entry(transaction models.TransactionResult) error {
+	if err := s.encoder.entry(transaction); err != nil {
+		return err
+	}
+
+	if s.pending++; s.pending >= statementFlushEvery {
+		return s.flush()
+	}
+
+	return nil
+}
+
+func (s *statementWriter) Closing(at time.Time, balance float64) error {
+	return s.encoder.closing(at, balance)
+}
+
+func (s *statementWriter) flush() error {
+	s.pending = 0
+
+	if err := s.encoder.flush(); err != nil {
+		return err
+	}
+
+	if err := http.NewResponseController(s.w).Flush(); err != nil {
+		return fmt.Errorf("flushing statement err: %w", err)
+	}
+
+	return nil
+}
+
+type csvStatementEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvStatementEncoder) contentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e *csvStatementEncoder) begin(w http.ResponseWriter) {
+	e.w = csv.NewWriter(w)
+}
+
+func (e *csvStatementEncoder) opening(at time.Time, balance float64) error {
+	header := []string{"record", "transaction_id", "executed_at", "transaction_type", "amount", "balance"}
+	if err := e.w.Write(header); err != nil {
+		return fmt.Errorf("writing csv header err: %w", err)
+	}
+
+	return e.balance("opening", at, balance)
+}
+
+func (e *csvStatementEncoder) entry(transaction models.TransactionResult) error {
+	err := e.w.Write([]string{
+		"transaction",
+		transaction.TransactionID.String(),
+		transaction.ExecutedAt.Format(time.RFC3339Nano),
+		transaction.OperationType,
+		formatAmount(transaction.Amount),
+		formatAmount(transaction.BalanceAfter),
+	})
+	if err != nil {
+		return fmt.Errorf("writing csv record err: %w", err)
+	}
+
+	return nil
+}
+
+func (e *csvStatementEncoder) closing(at time.Time, balance float64) error {
+	return e.balance("closing", at, balance)
+}
+
+func (e *csvStatementEncoder) balance(record string, at time.Time, balance float64) error {
+	if err := e.w.Write([]string{record, "", at.Format(time.RFC3339Nano), "", "", formatAmount(balance)}); err != nil {
+		return fmt.Errorf("writing csv %s balance err: %w", record, err)
+	}
+
+	return nil
+}
+
+func (e *csvStatementEncoder) flush() error {
+	e.w.Flush()
+
+	if err := e.w.Error(); err != nil {
+		return fmt.Errorf("flushing csv err: %w", err)
+	}
+
+	return nil
+}
+
+type jsonlStatementEncoder struct {
+	enc *json.Encoder
+}
+
+type statementBalanceRecord struct {
+	Record  string    `json:"record"`
+	At      time.Time `json:"at"`
+	Balance float64   `json:"balance"`
+}
+
+type statementEntryRecord struct {
+	Record string `json:"record"`
+	models.TransactionResult
+}
+
+func (e *jsonlStatementEncoder) contentType() string {
+	return "application/x-ndjson"
+}
+
+func (e *jsonlStatementEncoder) begin(w http.ResponseWriter) {
+	e.enc = json.NewEncoder(w)
+}
+
+func (e *jsonlStatementEncoder) opening(at time.Time, balance float64) error {
+	return e.encode(statementBalanceRecord{Record: "opening", At: at, Balance: balance})
+}
+
+func (e *jsonlStatementEncoder) entry(transaction models.TransactionResult) error {
+	return e.encode(statementEntryRecord{Record: "transaction", TransactionResult: transaction})
+}
+
+func (e *jsonlStatementEncoder) closing(at time.Time, balance float64) error {
+	return e.encode(statementBalanceRecord{Record: "closing", At: at, Balance: balance})
+}
+
+func (e *jsonlStatementEncoder) encode(record any) error {
+	if err := e.enc.Encode(record); err != nil {
+		return fmt.Errorf("writing jsonl record err: %w", err)
+	}
+
+	return nil
+}
+
+func (e *jsonlStatementEncoder) flush() error {
+	return nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+type TransactionsPage struct {
+	Transactions []models.TransactionResult `json:"transactions"`
+	NextCursor   string                     `json:"nextCursor,omitempty"`
+}
+
+func (s *Server) getTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidationError(w, r, "invalid transaction id", FieldError{Field: "id", Message: "must be a valid UUID"})
+
+		return
+	}
+
+	transaction, err := s.service.GetTransaction(r.Context(), transactionID)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, transaction)
+}
+
+// getWalletTransaction returns a transaction of a wallet to the clients of the wallet, unlike
+// getTransaction, which is for admins. New transactions point here, so it reads from the primary.
+func (s *Server) getWalletTransaction(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	transactionID, err := uuid.Parse(chi.URLParam(r, "transactionId"))
+	if err != nil {
+		writeValidationError(w, r, "invalid transaction id", FieldError{Field: "transactionId", Message: "must be a valid UUID"})
+
+		return
+	}
+
+	transaction, err := s.service.GetTransaction(models.WithReadYourWrites(r.Context()), transactionID)
+	if err == nil && transaction.WalletID != walletID {
+		err = models.ErrTransactionNotFound
+	}
+
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, transaction)
+}
+
+func (s *Server) listWalletTransactions(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	query := queryParser{values: r.URL.Query()}
+	params := query.historyParams()
+
+	if len(query.errors) > 0 {
+		writeValidationError(w, r, "invalid query parameters", query.errors...)
+
+		return
+	}
+
+	page, err := s.service.ListTransactions(r.Context(), walletID, params)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, newTransactionsPage(page))
+}
+
+func (s *Server) searchTransactions(w http.ResponseWriter, r *http.Request) {
+	query := queryParser{values: r.URL.Query()}
+
+	filter := models.TransactionFilter{
+		WalletID:      query.uuid("walletId"),
+		OperationType: query.string("transactionType"),
+		MinAmount:     query.float("minAmount"),
+		MaxAmount:     query.float("maxAmount"),
+		From:          query.time("from"),
+		To:            query.time("to"),
+	}
+
+	params := query.historyParams()
+
+	if len(query.errors) > 0 {
+		writeValidationError(w, r, "invalid query parameters", query.errors...)
+
+		return
+	}
+
+	page, err := s.service.SearchTransactions(r.Context(), filter, params)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	writeOkResponse(w, http.StatusOK, newTransactionsPage(page))
+}
+
+func newTransactionsPage(page *models.TransactionsPage) TransactionsPage {
+	resp := TransactionsPage{Transactions: page.Transactions}
+
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+	}
+
+	return resp
+}
+
+// queryParser reads optional typed query parameters and collects every malformed one, so a
+// client gets all problems with a request at once.
+type queryParser struct {
+	values url.Values
+	errors []FieldError
+}
+
+func (q *queryParser) string(name string) *string {
+	if !q.values.Has(name) {
+		return nil
+	}
+
+	value := q.values.Get(name)
+
+	return &value
+}
+
+func (q *queryParser) uuid(name string) *uuid.UUID {
+	if !q.values.Has(name) {
+		return nil
+	}
+
+	value, err := uuid.Parse(q.values.Get(name))
+	if err != nil {
+		q.errors = append(q.errors, FieldError{Field: name, Message: "must be a valid UUID"})
+
+		return nil
+	}
+
+	return &value
+}
+
+func (q *queryParser) float(name string) *float64 {
+	if !q.values.Has(name) {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(q.values.Get(name), 64)
+	if err != nil {
+		q.errors = append(q.errors, FieldError{Field: name, Message: "must be a number"})
+
+		return nil
+	}
+
+	return &value
+}
+
+func (q *queryParser) int(name string) int {
+	if !q.values.Has(name) {
+		return 0
+	}
+
+	value, err := strconv.Atoi(q.values.Get(name))
+	if err != nil {
+		q.errors = append(q.errors, FieldError{Field: name, Message: "must be an integer"})
+
+		return 0
+	}
+
+	return value
+}
+
+func (q *queryParser) time(name string) *time.Time {
+	if !q.values.Has(name) {
+		return nil
+	}
+
+	value, err := time.Parse(time.RFC3339, q.values.Get(name))
+	if err != nil {
+		q.errors = append(q.errors, FieldError{Field: name, Message: "must be an RFC 3339 timestamp"})
+
+		return nil
+	}
+
+	// Execution times are kept in timestamp columns without a time zone, holding the server's local
+	// time, so the instant is passed in local time whatever offset the client wrote it with.
+	value = value.In(time.Local)
+
+	return &value
+}
+
+func (q *queryParser) historyParams() models.HistoryParams {
+	params := models.HistoryParams{Limit: q.int("limit")}
+
+	if q.values.Has("cursor") {
+		cursor, err := models.ParseTransactionCursor(q.values.Get("cursor"))
+		if err != nil {
+			q.errors = append(q.errors, FieldError{Field: "cursor", Message: err.Error()})
+		}
+
+		params.After = cursor
+	}
+
+	return params
+}
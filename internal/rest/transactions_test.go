@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTimesAreLocal(t *testing.T) {
+	query := &queryParser{values: url.Values{
+		"from": {"2026-01-02T03:04:05+03:00"},
+		"to":   {"2026-01-02T00:04:05Z"},
+	}}
+
+	from, to := query.time("from"), query.time("to")
+	require.Empty(t, query.errors)
+
+	require.True(t, from.Equal(*to))
+	require.Equal(t, time.Local, from.Location())
+	require.Equal(t, time.Date(2026, 1, 2, 0, 4, 5, 0, time.UTC).In(time.Local), *from)
+}
+
+// transactionsService holds a single transaction and records whether it is read from the primary
+// database.
+type transactionsService struct {
+	service
+	transaction    models.TransactionResult
+	readYourWrites bool
+}
+
+func (s *transactionsService) GetTransaction(ctx context.Context, id uuid.UUID) (*models.TransactionResult, error) {
+	s.readYourWrites = models.ReadYourWrites(ctx)
+
+	if id != s.transaction.TransactionID {
+		return nil, models.ErrTransactionNotFound
+	}
+
+	return &s.transaction, nil
+}
+
+func TestGetWalletTransaction(t *testing.T) {
+	transaction := models.Transaction{TransactionID: uuid.New(), WalletID: uuid.New()}
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{
+			name:   "the Location of a new transaction",
+			path:   transactionLocation(httptest.NewRequest(http.MethodPost, "/api/v2/", nil), transaction),
+			status: http.StatusOK,
+		},
+		{
+			name:   "a transaction of another wallet",
+			path:   "/api/v1/wallets/" + uuid.NewString() + "/transactions/" + transaction.TransactionID.String(),
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "not a transaction id",
+			path:   "/api/v1/wallets/" + transaction.WalletID.String() + "/transactions/abc",
+			status: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &transactionsService{transaction: models.TransactionResult{Transaction: transaction}}
+
+			srv, err := NewServer(ServerConfig{}, svc, nil)
+			require.NoError(t, err)
+
+			srv.configRouter()
+
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			require.Equal(t, tc.status != http.StatusBadRequest, svc.readYourWrites)
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+// apiVersion is a version of the API, served under /api/<name>. All versions share the handlers,
+// which speak the format of the first version; the adapters of later versions translate request
+// bodies from and response bodies to their own format.
+type apiVersion struct {
+	name string
+	// legacy is set for versions that still serve the routes replaced by per-wallet routes.
+	legacy bool
+	// adaptRequest and adaptResponse rewrite decoded JSON bodies. Nil leaves bodies unchanged.
+	adaptRequest  func(body any) any
+	adaptResponse func(body any) any
+	// adaptSpec turns the OpenAPI document of the handlers into that of the version.
+	adaptSpec func(doc *openapi3.T)
+}
+
+// apiVersions are the versions served, oldest first.
+//
+//nolint:gochecknoglobals
+var apiVersions = []apiVersion{
+	{name: "v1", legacy: true},
+	{
+		// v2 sends amounts as decimal strings, which clients can parse without losing precision.
+		name:          "v2",
+		adaptRequest:  decimalAmounts(false),
+		adaptResponse: decimalAmounts(true),
+		adaptSpec:     decimalAmountsSpec,
+	},
+}
+
+// legacySuccessors are the routes that replace the legacy routes, which are always deprecated. The
+// {id} of a successor is the wallet the request is for.
+//
+//nolint:gochecknoglobals
+var legacySuccessors = map[string]string{
+	"PUT /api/v1/wallets/deposit":  "/api/v1/wallets/{id}/deposits",
+	"PUT /api/v1/wallets/withdraw": "/api/v1/wallets/{id}/withdrawals",
+}
+
+//nolint:gochecknoglobals
+var deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "wallets_http_deprecated_requests_total",
+	Help: "Number of requests to deprecated routes, by route.",
+}, []string{"route"})
+
+func (v apiVersion) prefix() string {
+	return "/api/" + v.name
+}
+
+// requestVersion returns the API version r is for, or the first version for requests outside the API.
+func requestVersion(r *http.Request) apiVersion {
+	for _, version := range apiVersions {
+		if strings.HasPrefix(r.URL.Path, version.prefix()+"/") {
+			return version
+		}
+	}
+
+	return apiVersions[0]
+}
+
+// adapt translates the JSON bodies of requests and responses between the version and the handlers.
+// Bodies that are not JSON, or not valid JSON, are passed on unchanged.
+func (v apiVersion) adapt(next http.Handler) http.Handler {
+	if v.adaptRequest == nil && v.adaptResponse == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v.adaptRequest != nil && r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeDecodeError(w, r, err)
+
+				return
+			}
+
+			if adapted, err := adaptJSON(body, v.adaptRequest); err == nil {
+				body = adapted
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+
+		if v.adaptResponse == nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		aw := &adaptingWriter{ResponseWriter: w, adapt: v.adaptResponse}
+		next.ServeHTTP(aw, r)
+		aw.finish()
+	})
+}
+
+func adaptJSON(body []byte, adapt func(any) any) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("decoder.Decode(&value) err: %w", err)
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errTrailingData
+	}
+
+	adapted, err := json.Marshal(adapt(value))
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(adapt(value)) err: %w", err)
+	}
+
+	return append(adapted, '\n'), nil
+}
+
+// adaptingWriter holds back JSON responses until the handler is done, so that they can be adapted
+// as a whole. Other responses, such as statements, are streamed unchanged.
+type adaptingWriter struct {
+	http.ResponseWriter
+	adapt func(body any) any
+
+	statusCode int
+	buffer     *bytes.Buffer
+}
+
+func (aw *adaptingWriter) WriteHeader(statusCode int) {
+	if aw.statusCode != 0 {
+		return
+	}
+
+	aw.statusCode = statusCode
+
+	if mediaType, _, err := mime.ParseMediaType(aw.Header().Get("Content-Type")); err == nil && mediaType == jsonMediaType {
+		aw.buffer = new(bytes.Buffer)
+
+		return
+	}
+
+	aw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (aw *adaptingWriter) Write(b []byte) (int, error) {
+	aw.WriteHeader(http.StatusOK)
+
+	var (
+		n   int
+		err error
+	)
+
+	if aw.buffer != nil {
+		n, err = aw.buffer.Write(b)
+	} else {
+		n, err = aw.ResponseWriter.Write(b)
+	}
+
+	if err != nil {
+		return n, fmt.Errorf("write err: %w", err)
+	}
+
+	return n, nil
+}
+
+// Unwrap lets http.ResponseController reach the flusher of the underlying writer.
+func (aw *adaptingWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+func (aw *adaptingWriter) finish() {
+	if aw.buffer == nil {
+		return
+	}
+
+	body := aw.buffer.Bytes()
+	if adapted, err := adaptJSON(body, aw.adapt); err == nil {
+		body = adapted
+	}
+
+	aw.Header().Del("Content-Length")
+	aw.ResponseWriter.WriteHeader(aw.statusCode)
+
+	if _, err := aw.ResponseWriter.Write(body); err != nil {
+		log.Warnf("aw.ResponseWriter.Write(body) err: %v", err)
+	}
+}
+
+// amountFields are the JSON fields that hold amounts of money.
+//
+//nolint:gochecknoglobals
+var amountFields = map[string]struct{}{
+	"amount":          {},
+	"Balance":         {},
+	"PendingBalance":  {},
+	"balance":         {},
+	"balanceAfter":    {},
+	"storedBalance":   {},
+	"computedBalance": {},
+	"delta":           {},
+}
+
+// opaqueFields are the JSON fields that hold data of the client, which is never adapted.
+//
+//nolint:gochecknoglobals
+var opaqueFields = map[string]struct{}{
+	"metadata": {},
+	"Metadata": {},
+}
+
+const decimalPattern = `^-?[0-9]+(\.[0-9]+)?$`
+
+// decimalAmounts returns an adapter that turns the amounts of a JSON body into decimal strings, or
+// with toDecimals false, decimal strings back into numbers.
+func decimalAmounts(toDecimals bool) func(body any) any {
+	var adapt func(body any) any
+
+	adapt = func(body any) any {
+		switch value := body.(type) {
+		case map[string]any:
+			for key, field := range value {
+				if _, ok := opaqueFields[key]; ok {
+					continue
+				}
+
+				if _, ok := amountFields[key]; !ok {
+					value[key] = adapt(field)
+
+					continue
+				}
+
+				switch amount := field.(type) {
+				case json.Number:
+					if f, err := amount.Float64(); err == nil && toDecimals {
+						value[key] = formatAmount(f)
+					}
+				case string:
+					if _, err := strconv.ParseFloat(amount, 64); err == nil && !toDecimals {
+						value[key] = json.Number(amount)
+					}
+				}
+			}
+		case []any:
+			for i := range value {
+				value[i] = adapt(value[i])
+			}
+		}
+
+		return body
+	}
+
+	return adapt
+}
+
+// decimalAmountsSpec describes the amounts of the document as decimal strings.
+func decimalAmountsSpec(doc *openapi3.T) {
+	seen := make(map[*openapi3.Schema]struct{})
+
+	var walk func(schema *openapi3.Schema)
+
+	walk = func(schema *openapi3.Schema) {
+		if _, ok := seen[schema]; ok {
+			return
+		}
+
+		seen[schema] = struct{}{}
+
+		for name, property := range schema.Properties {
+			if _, ok := amountFields[name]; ok && property.Value.Type.Is(openapi3.TypeNumber) {
+				property.Value = &openapi3.Schema{
+					Type:        &openapi3.Types{openapi3.TypeString},
+					Pattern:     decimalPattern,
+					Description: property.Value.Description,
+				}
+
+				continue
+			}
+
+			walk(property.Value)
+		}
+
+		for _, ref := range schema.AllOf {
+			walk(ref.Value)
+		}
+
+		if schema.Items != nil {
+			walk(schema.Items.Value)
+		}
+	}
+
+	for _, schema := range doc.Components.Schemas {
+		walk(schema.Value)
+	}
+}
+
+// deprecate announces the deprecation of the route of a request with the Deprecation header, its
+// sunset with the Sunset header and the route that replaces it with a Link header.
+func (s *Server) deprecate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.NewRouteContext()
+		if !s.router.Match(rctx, r.Method, r.URL.Path) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		route := r.Method + " " + rctx.RoutePattern()
+
+		deprecation, ok := s.deprecations[route]
+		if !ok {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		deprecatedRequests.WithLabelValues(route).Inc()
+
+		w.Header().Set("Deprecation", "true")
+
+		if !deprecation.Sunset.IsZero() {
+			w.Header().Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+
+		successor, err := s.successor(r, route)
+		if err != nil {
+			writeDecodeError(w, r, err)
+
+			return
+		}
+
+		if successor != "" {
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// successor returns the URL that replaces the route of r: the same route of the latest version
+// if there is one. The successors of legacy routes are the routes of the wallet in the body, so
+// the body is read and put back for the handler; without a wallet there is no successor.
+func (s *Server) successor(r *http.Request, route string) (string, error) {
+	if successor, ok := legacySuccessors[route]; ok {
+		walletID, err := bodyWalletID(r)
+		if err != nil || walletID == uuid.Nil {
+			return "", err
+		}
+
+		return strings.Replace(successor, "{id}", walletID.String(), 1), nil
+	}
+
+	latest := apiVersions[len(apiVersions)-1].prefix()
+
+	for _, version := range apiVersions {
+		path, ok := strings.CutPrefix(r.URL.Path, version.prefix()+"/")
+		if !ok || version.prefix() == latest {
+			continue
+		}
+
+		if s.router.Match(chi.NewRouteContext(), r.Method, latest+"/"+path) {
+			return latest + "/" + path, nil
+		}
+	}
+
+	return "", nil
+}
+
+// bodyWalletID returns the walletId of the JSON body of r, or uuid.Nil if there is none.
+func bodyWalletID(r *http.Request) (uuid.UUID, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return uuid.Nil, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("io.ReadAll(r.Body) err: %w", err)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var named struct {
+		WalletID uuid.UUID `json:"walletId"`
+	}
+
+	// Invalid bodies are reported by the handler.
+	if err := json.Unmarshal(body, &named); err != nil {
+		return uuid.Nil, nil
+	}
+
+	return named.WalletID, nil
+}
+
+// deprecations indexes the deprecated routes by method and route. The legacy routes are always
+// deprecated.
+func deprecations(configured []models.Deprecation) map[string]models.Deprecation {
+	result := make(map[string]models.Deprecation, len(legacySuccessors)+len(configured))
+
+	for route := range legacySuccessors {
+		method, pattern, _ := strings.Cut(route, " ")
+		result[route] = models.Deprecation{Method: method, Route: pattern}
+	}
+
+	for _, deprecation := range configured {
+		result[deprecation.Method+" "+deprecation.Route] = deprecation
+	}
+
+	return result
+}
@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimalAmounts(t *testing.T) {
+	v2 := apiVersions[1]
+
+	handler := v2.adapt(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var transaction models.Transaction
+
+		if !decodeJSON(w, r, &transaction) {
+			return
+		}
+
+		writeOkResponse(w, http.StatusCreated, models.TransactionResult{Transaction: transaction, BalanceAfter: 0.3})
+	}))
+
+	t.Run("adapted", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v2/wallets/deposit", strings.NewReader(`{"amount": "12.5", "reason": "1"}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(t, "12.5", resp.Data["amount"])
+		require.Equal(t, "0.3", resp.Data["balanceAfter"])
+		require.Equal(t, "1", resp.Data["reason"])
+	})
+
+	t.Run("metadata is not adapted", func(t *testing.T) {
+		adapted, err := adaptJSON([]byte(`{"Balance": 1, "Metadata": {"amount": 2}}`), v2.adaptResponse)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Balance": "1", "Metadata": {"amount": 2}}`, string(adapted))
+	})
+
+	t.Run("not a decimal", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v2/wallets/deposit", strings.NewReader(`{"amount": "ten"}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("not JSON", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v2/wallets/deposit", strings.NewReader(`{"amount": `))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "request body is malformed")
+	})
+}
+
+func TestVersionedOpenAPI(t *testing.T) {
+	srv, err := NewServer(ServerConfig{}, nil, nil)
+	require.NoError(t, err)
+
+	v1, v2 := srv.openAPI["v1"].doc, srv.openAPI["v2"].doc
+
+	require.NotNil(t, v1.Paths.Find("/api/v1/wallets/deposit"))
+	require.Nil(t, v2.Paths.Find("/api/v2/wallets/deposit"))
+	require.NotNil(t, v2.Paths.Find("/api/v2/wallets/{id}/deposits"))
+	require.NotNil(t, v2.Paths.Find("/healthz"))
+
+	amount := func(doc *openapi3.T) string {
+		return doc.Components.Schemas["Transaction"].Value.Properties["amount"].Value.Type.Slice()[0]
+	}
+
+	require.Equal(t, "number", amount(v1))
+	require.Equal(t, "string", amount(v2))
+}
+
+func TestDeprecate(t *testing.T) {
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	srv, err := NewServer(ServerConfig{Deprecations: []models.Deprecation{
+		{Method: http.MethodGet, Route: "/api/v1/openapi.json", Sunset: sunset},
+	}}, nil, nil)
+	require.NoError(t, err)
+
+	srv.configRouter()
+
+	for _, tc := range []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		deprecated bool
+		sunset     string
+		successor  string
+	}{
+		{
+			name: "configured", method: http.MethodGet, path: "/api/v1/openapi.json", deprecated: true,
+			sunset: "Wed, 30 Jun 2027 00:00:00 GMT", successor: "</api/v2/openapi.json>; rel=\"successor-version\"",
+		},
+		{
+			name: "legacy", method: http.MethodPut, path: "/api/v1/wallets/deposit", deprecated: true,
+			body:      `{"walletId": "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "amount": 1}`,
+			successor: "</api/v1/wallets/6ba7b810-9dad-11d1-80b4-00c04fd430c8/deposits>; rel=\"successor-version\"",
+		},
+		{
+			name: "legacy without a wallet", method: http.MethodPut, path: "/api/v1/wallets/deposit", deprecated: true,
+		},
+		{name: "not deprecated", method: http.MethodGet, path: "/api/v2/openapi.json"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(cmp.Or(tc.body, "{}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, r)
+
+			if !tc.deprecated {
+				require.Empty(t, w.Header().Get("Deprecation"))
+
+				return
+			}
+
+			require.Equal(t, "true", w.Header().Get("Deprecation"))
+			require.Equal(t, tc.sunset, w.Header().Get("Sunset"))
+			require.Equal(t, tc.successor, w.Header().Get("Link"))
+		})
+	}
+}
+
+func TestTransactionLocation(t *testing.T) {
+	transaction := models.Transaction{TransactionID: uuid.New(), WalletID: uuid.New()}
+	wallet := "/wallets/" + transaction.WalletID.String()
+
+	for path, want := range map[string]string{
+		"/api/v1" + wallet + "/deposits": "/api/v1" + wallet + "/transactions/" + transaction.TransactionID.String(),
+		"/api/v2" + wallet + "/deposits": "/api/v2" + wallet + "/transactions/" + transaction.TransactionID.String(),
+	} {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		require.Equal(t, want, transactionLocation(r, transaction))
+	}
+}
@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/iurikman/wallets/internal/models"
+)
+
+// legacyWalletFields are the fields of the whole wallet that clients of the legacy versions send to
+// create a wallet. They were always ignored, and still are.
+//
+//nolint:gochecknoglobals
+var legacyWalletFields = func() map[string]struct{} {
+	wallet := reflect.TypeFor[models.Wallet]()
+	fields := make(map[string]struct{}, wallet.NumField())
+
+	for i := range wallet.NumField() {
+		fields[wallet.Field(i).Name] = struct{}{}
+	}
+
+	return fields
+}()
+
+type WalletsPage struct {
+	Wallets    []models.Wallet `json:"wallets"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// updateWallet replaces the attributes given in the body. With If-Match the wallet is only
+// updated at one of the given versions.
+func (s *Server) updateWallet(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var attrs models.WalletAttributes
+
+	if !decodeValid(w, r, &attrs) {
+		return
+	}
+
+	wallet, err := s.service.UpdateWallet(ifMatch(r), walletID, attrs)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	w.Header().Set("ETag", walletETag(wallet.Version))
+	writeOkResponse(w, http.StatusOK, wallet)
+}
+
+func (s *Server) listWallets(w http.ResponseWriter, r *http.Request) {
+	query := queryParser{values: r.URL.Query()}
+
+	filter := models.WalletFilter{
+		Label:       query.string("label"),
+		ExternalRef: query.string("external_ref"),
+	}
+
+	params := query.walletsParams()
+
+	if len(query.errors) > 0 {
+		writeValidationError(w, r, "invalid query parameters", query.errors...)
+
+		return
+	}
+
+	page, err := s.service.ListWallets(r.Context(), filter, params)
+	if err != nil {
+		writeError(w, r, err)
+
+		return
+	}
+
+	resp := WalletsPage{Wallets: page.Wallets}
+
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+	}
+
+	writeOkResponse(w, http.StatusOK, resp)
+}
+
+func (q *queryParser) walletsParams() models.WalletsParams {
+	params := models.WalletsParams{Limit: q.int("limit")}
+
+	if q.values.Has("cursor") {
+		cursor, err := models.ParseWalletCursor(q.values.Get("cursor"))
+		if err != nil {
+			q.errors = append(q.errors, FieldError{Field: "cursor", Message: err.Error()})
+		}
+
+		params.After = cursor
+	}
+
+	return params
+}
+
+// dropLegacyWalletFields removes the legacy wallet fields from the JSON object in the body of r.
+// Their names only differ in case from some of the wallet attributes, which encoding/json would
+// take them for. Bodies that are not JSON objects are left for the decoder to reject.
+func dropLegacyWalletFields(w http.ResponseWriter, r *http.Request) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeDecodeError(w, r, err)
+
+		return false
+	}
+
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &fields); err == nil && fields != nil {
+		for name := range fields {
+			if _, ok := legacyWalletFields[name]; ok {
+				delete(fields, name)
+			}
+		}
+
+		if body, err = json.Marshal(fields); err != nil {
+			writeError(w, r, fmt.Errorf("json.Marshal(fields) err: %w", err))
+
+			return false
+		}
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	return true
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+// walletsService records the attributes wallets are created with.
+type walletsService struct {
+	service
+	created []models.WalletAttributes
+}
+
+func (s *walletsService) CreateWallet(_ context.Context, attrs models.WalletAttributes) (*models.Wallet, error) {
+	s.created = append(s.created, attrs)
+
+	return &models.Wallet{Version: 1}, nil
+}
+
+func TestCreateWalletLegacyBody(t *testing.T) {
+	// Clients of the first version send the whole wallet.
+	body, err := json.Marshal(models.Wallet{})
+	require.NoError(t, err)
+
+	legacy := string(body)
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		labels []string
+	}{
+		{name: "v1 ignores a whole wallet", path: "/api/v1/wallets/", body: legacy, status: http.StatusCreated},
+		{
+			name: "v1 keeps the attributes", path: "/api/v1/wallets/", body: `{"Balance": 0, "labels": ["merchant"]}`,
+			status: http.StatusCreated, labels: []string{"merchant"},
+		},
+		{name: "v1 rejects other fields", path: "/api/v1/wallets/", body: `{"lables": []}`, status: http.StatusBadRequest},
+		{name: "v2 takes attributes only", path: "/api/v2/wallets/", body: legacy, status: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &walletsService{}
+
+			srv, err := NewServer(ServerConfig{}, svc, nil)
+			require.NoError(t, err)
+
+			srv.configRouter()
+
+			r := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, r)
+
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+
+			if tc.status != http.StatusCreated {
+				require.Empty(t, svc.created)
+
+				return
+			}
+
+			require.Len(t, svc.created, 1)
+			require.Nil(t, svc.created[0].Metadata)
+
+			if tc.labels == nil {
+				require.Nil(t, svc.created[0].Labels)
+
+				return
+			}
+
+			require.Equal(t, tc.labels, *svc.created[0].Labels)
+		})
+	}
+}
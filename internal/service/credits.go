@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrCreditApplierNotRunning = errors.New("pending credits applier is not running")
+
+//nolint:gochecknoglobals
+var (
+	pendingCreditsApplied = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wallets_pending_credits_applied_total",
+		Help: "Number of hot wallet deposits applied to wallet balances.",
+	})
+	pendingCreditsErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wallets_pending_credits_errors_total",
+		Help: "Number of failed runs applying pending credits.",
+	})
+)
+
+type creditsDB interface {
+	ApplyPendingCredits(ctx context.Context, limit int) (int, error)
+}
+
+type CreditApplierConfig struct {
+	// Interval between runs of the background job. Zero disables the job.
+	Interval time.Duration
+	// BatchSize is the number of credits applied to a wallet at most in one transaction.
+	BatchSize int
+}
+
+// CreditApplier adds the pending credits of hot wallets to their balances.
+type CreditApplier struct {
+	db      creditsDB
+	config  CreditApplierConfig
+	running atomic.Bool
+}
+
+func NewCreditApplier(db creditsDB, config CreditApplierConfig) *CreditApplier {
+	return &CreditApplier{
+		db:     db,
+		config: config,
+	}
+}
+
+// Start applies pending credits every configured interval until ctx is canceled.
+func (a *CreditApplier) Start(ctx context.Context) error {
+	if a.config.Interval <= 0 {
+		log.Info("pending credits applier is disabled")
+
+		return nil
+	}
+
+	a.running.Store(true)
+	defer a.running.Store(false)
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.Apply(ctx); err != nil && ctx.Err() == nil {
+			log.Warnf("applying pending credits failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Apply runs one pass over the wallets with pending credits and returns how many were applied.
+func (a *CreditApplier) Apply(ctx context.Context) (int, error) {
+	applied, err := a.db.ApplyPendingCredits(ctx, a.config.BatchSize)
+
+	pendingCreditsApplied.Add(float64(applied))
+
+	if err != nil {
+		pendingCreditsErrors.Inc()
+
+		return applied, fmt.Errorf("a.db.ApplyPendingCredits() err: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Check reports whether the background job is running when it is enabled.
+func (a *CreditApplier) Check(_ context.Context) error {
+	if a.config.Interval > 0 && !a.running.Load() {
+		return ErrCreditApplierNotRunning
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCreditsDB struct {
+	limits  []int
+	applied int
+	err     error
+}
+
+func (f *fakeCreditsDB) ApplyPendingCredits(_ context.Context, limit int) (int, error) {
+	f.limits = append(f.limits, limit)
+
+	return f.applied, f.err
+}
+
+func TestCreditApplierApply(t *testing.T) {
+	t.Run("applies a batch", func(t *testing.T) {
+		db := &fakeCreditsDB{applied: 3}
+		a := NewCreditApplier(db, CreditApplierConfig{BatchSize: 100})
+
+		applied, err := a.Apply(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 3, applied)
+		require.Equal(t, []int{100}, db.limits)
+	})
+
+	t.Run("reports what was applied before an error", func(t *testing.T) {
+		errDB := errors.New("connection lost")
+		a := NewCreditApplier(&fakeCreditsDB{applied: 2, err: errDB}, CreditApplierConfig{BatchSize: 100})
+
+		applied, err := a.Apply(context.Background())
+		require.ErrorIs(t, err, errDB)
+		require.Equal(t, 2, applied)
+	})
+}
+
+func TestCreditApplierStart(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		a := NewCreditApplier(&fakeCreditsDB{}, CreditApplierConfig{})
+
+		require.NoError(t, a.Start(context.Background()))
+		require.NoError(t, a.Check(context.Background()))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		db := &fakeCreditsDB{}
+		a := NewCreditApplier(db, CreditApplierConfig{Interval: time.Hour, BatchSize: 10})
+
+		require.ErrorIs(t, a.Check(context.Background()), ErrCreditApplierNotRunning)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+
+		go func() { done <- a.Start(ctx) }()
+
+		require.Eventually(t, func() bool { return a.Check(context.Background()) == nil }, time.Second, time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+		require.Equal(t, []int{10}, db.limits)
+	})
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrReconcilerNotRunning = errors.New("reconciler is not running")
+
+//nolint:gochecknoglobals
+var (
+	reconciliationRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallets_reconciliation_runs_total",
+		Help: "Number of ledger reconciliation runs by result.",
+	}, []string{"result"})
+	reconciliationLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "wallets_reconciliation_last_run_timestamp_seconds",
+		Help: "Unix time the last ledger reconciliation run finished.",
+	})
+	reconciliationChecked = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "wallets_reconciliation_wallets_checked",
+		Help: "Number of wallets checked by the last ledger reconciliation run.",
+	})
+	reconciliationDrifted = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "wallets_reconciliation_drifted_wallets",
+		Help: "Number of wallets whose balance did not match their history in the last run.",
+	})
+)
+
+type reconciliationDB interface {
+	ReconcileBalances(ctx context.Context) ([]models.BalanceDrift, int, error)
+	FreezeWallets(ctx context.Context, ids []uuid.UUID) (int, error)
+}
+
+type ReconcilerConfig struct {
+	// Interval between runs of the background job. Zero disables the job.
+	Interval time.Duration
+	// FreezeDrifted blocks balance changes of wallets found to have drifted.
+	FreezeDrifted bool
+}
+
+// Reconciler verifies that the balance of every wallet equals the sum of its history.
+type Reconciler struct {
+	db     reconciliationDB
+	config ReconcilerConfig
+
+	mu      sync.Mutex
+	last    *models.ReconciliationReport
+	running atomic.Bool
+}
+
+func NewReconciler(db reconciliationDB, config ReconcilerConfig) *Reconciler {
+	return &Reconciler{
+		db:     db,
+		config: config,
+	}
+}
+
+// Start runs reconciliation every configured interval until ctx is canceled.
+func (r *Reconciler) Start(ctx context.Context) error {
+	if r.config.Interval <= 0 {
+		log.Info("ledger reconciliation job is disabled")
+
+		return nil
+	}
+
+	r.running.Store(true)
+	defer r.running.Store(false)
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			log.Warnf("ledger reconciliation failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile runs one reconciliation pass and records its report as the last run.
+func (r *Reconciler) Reconcile(ctx context.Context) (*models.ReconciliationReport, error) {
+	report := &models.ReconciliationReport{StartedAt: time.Now(), Drifts: make([]models.BalanceDrift, 0)}
+
+	err := r.reconcile(ctx, report)
+
+	report.FinishedAt = time.Now()
+
+	if err != nil {
+		report.Error = err.Error()
+		reconciliationRuns.WithLabelValues("error").Inc()
+	} else {
+		reconciliationRuns.WithLabelValues("ok").Inc()
+		reconciliationChecked.Set(float64(report.WalletsChecked))
+		reconciliationDrifted.Set(float64(len(report.Drifts)))
+	}
+
+	reconciliationLastRun.Set(float64(report.FinishedAt.Unix()))
+
+	r.mu.Lock()
+	r.last = report
+	r.mu.Unlock()
+
+	return report, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, report *models.ReconciliationReport) error {
+	drifts, checked, err := r.db.ReconcileBalances(ctx)
+	if err != nil {
+		return fmt.Errorf("r.db.ReconcileBalances(ctx) err: %w", err)
+	}
+
+	report.WalletsChecked = checked
+	report.Drifts = drifts
+
+	for _, drift := range drifts {
+		log.Warnf(
+			"wallet %s balance drifted: stored %v, computed from history %v, delta %v",
+			drift.WalletID, drift.StoredBalance, drift.ComputedBalance, drift.Delta,
+		)
+	}
+
+	if !r.config.FreezeDrifted || len(drifts) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(drifts))
+	for _, drift := range drifts {
+		ids = append(ids, drift.WalletID)
+	}
+
+	if report.FrozenWallets, err = r.db.FreezeWallets(ctx, ids); err != nil {
+		return fmt.Errorf("r.db.FreezeWallets(ctx, ids) err: %w", err)
+	}
+
+	return nil
+}
+
+// LastReport returns the report of the last run.
+func (r *Reconciler) LastReport() (*models.ReconciliationReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		return nil, models.ErrReconciliationNotRun
+	}
+
+	return r.last, nil
+}
+
+// Check reports whether the background job is running when it is enabled.
+func (r *Reconciler) Check(_ context.Context) error {
+	if r.config.Interval > 0 && !r.running.Load() {
+		return ErrReconcilerNotRunning
+	}
+
+	return nil
+}
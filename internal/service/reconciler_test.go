@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReconciliationDB struct {
+	drifts []models.BalanceDrift
+	err    error
+	frozen []uuid.UUID
+}
+
+func (f *fakeReconciliationDB) ReconcileBalances(_ context.Context) ([]models.BalanceDrift, int, error) {
+	return f.drifts, len(f.drifts) + 1, f.err
+}
+
+func (f *fakeReconciliationDB) FreezeWallets(_ context.Context, ids []uuid.UUID) (int, error) {
+	f.frozen = append(f.frozen, ids...)
+
+	return len(ids), nil
+}
+
+func TestReconcilerReconcile(t *testing.T) {
+	drift := models.BalanceDrift{WalletID: uuid.New(), StoredBalance: 10, ComputedBalance: 7, Delta: 3}
+
+	t.Run("reports drift without freezing", func(t *testing.T) {
+		db := &fakeReconciliationDB{drifts: []models.BalanceDrift{drift}}
+		r := NewReconciler(db, ReconcilerConfig{})
+
+		_, err := r.LastReport()
+		require.ErrorIs(t, err, models.ErrReconciliationNotRun)
+
+		report, err := r.Reconcile(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, report.WalletsChecked)
+		require.Equal(t, []models.BalanceDrift{drift}, report.Drifts)
+		require.Zero(t, report.FrozenWallets)
+		require.Empty(t, db.frozen)
+
+		last, err := r.LastReport()
+		require.NoError(t, err)
+		require.Same(t, report, last)
+	})
+
+	t.Run("freezes drifted wallets", func(t *testing.T) {
+		db := &fakeReconciliationDB{drifts: []models.BalanceDrift{drift}}
+		r := NewReconciler(db, ReconcilerConfig{FreezeDrifted: true})
+
+		report, err := r.Reconcile(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, report.FrozenWallets)
+		require.Equal(t, []uuid.UUID{drift.WalletID}, db.frozen)
+	})
+
+	t.Run("failed run is recorded", func(t *testing.T) {
+		r := NewReconciler(&fakeReconciliationDB{err: errors.New("connection refused")}, ReconcilerConfig{})
+
+		_, err := r.Reconcile(context.Background())
+		require.Error(t, err)
+
+		last, err := r.LastReport()
+		require.NoError(t, err)
+		require.Contains(t, last.Error, "connection refused")
+	})
+}
@@ -2,17 +2,37 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iurikman/wallets/internal/models"
 )
 
 type db interface {
-	CreateWallet(ctx context.Context) (*models.Wallet, error)
+	CreateWallet(ctx context.Context, attrs models.WalletAttributes) (*models.Wallet, error)
+	UpdateWallet(ctx context.Context, id uuid.UUID, attrs models.WalletAttributes) (*models.Wallet, error)
+	ListWallets(ctx context.Context, filter models.WalletFilter, params models.WalletsParams) (*models.WalletsPage, error)
 	GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
-	Deposit(ctx context.Context, transaction models.Transaction) error
-	Withdraw(ctx context.Context, transaction models.Transaction) error
+	Deposit(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error)
+	Withdraw(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error)
+	GetTransaction(ctx context.Context, id uuid.UUID) (*models.TransactionResult, error)
+	GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (float64, error)
+	WriteStatement(ctx context.Context, walletID uuid.UUID, period models.StatementPeriod, w models.StatementWriter) error
+	VerifyChain(ctx context.Context, walletID uuid.UUID) (*models.ChainVerification, error)
+	ExecuteBatch(ctx context.Context, batch models.Batch) ([]models.BatchResult, error)
+	ImportWallets(ctx context.Context, wallets []models.Wallet, dryRun bool) error
+	SetWalletFrozen(ctx context.Context, id uuid.UUID, frozen bool) (*models.Wallet, error)
+	SetWalletHot(ctx context.Context, id uuid.UUID, hot bool) (*models.Wallet, error)
+	CloseWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	ExportWallets(ctx context.Context, fn func(wallet models.Wallet) error) error
+	ListTransactions(
+		ctx context.Context,
+		filter models.TransactionFilter,
+		params models.HistoryParams,
+	) (*models.TransactionsPage, error)
 }
 
 type Service struct {
@@ -25,15 +45,49 @@ func New(db db) *Service {
 	}
 }
 
-func (s *Service) CreateWallet(ctx context.Context) (*models.Wallet, error) {
-	createdWallet, err := s.db.CreateWallet(ctx)
+func (s *Service) CreateWallet(ctx context.Context, attrs models.WalletAttributes) (*models.Wallet, error) {
+	if err := attrs.Validate(); err != nil {
+		return nil, fmt.Errorf("attrs.Validate() err: %w", err)
+	}
+
+	createdWallet, err := s.db.CreateWallet(ctx, attrs)
 	if err != nil {
-		return nil, fmt.Errorf("s.db.CreateWallet(ctx) err: %w", err)
+		return nil, fmt.Errorf("s.db.CreateWallet(ctx, attrs) err: %w", err)
 	}
 
 	return createdWallet, nil
 }
 
+func (s *Service) UpdateWallet(ctx context.Context, id uuid.UUID, attrs models.WalletAttributes) (*models.Wallet, error) {
+	if err := attrs.Validate(); err != nil {
+		return nil, fmt.Errorf("attrs.Validate() err: %w", err)
+	}
+
+	wallet, err := s.db.UpdateWallet(ctx, id, attrs)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.UpdateWallet() err: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (s *Service) ListWallets(
+	ctx context.Context,
+	filter models.WalletFilter,
+	params models.WalletsParams,
+) (*models.WalletsPage, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("params.Validate() err: %w", err)
+	}
+
+	page, err := s.db.ListWallets(ctx, filter, params)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.ListWallets() err: %w", err)
+	}
+
+	return page, nil
+}
+
 func (s *Service) GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
 	wallet, err := s.db.GetWallet(ctx, id)
 	if err != nil {
@@ -43,19 +97,270 @@ func (s *Service) GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet,
 	return wallet, nil
 }
 
-func (s *Service) Withdraw(ctx context.Context, transaction models.Transaction) error {
-	err := s.db.Withdraw(ctx, transaction)
+func (s *Service) Withdraw(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error) {
+	result, err := s.db.Withdraw(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.Withdraw() err: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetWalletFrozen blocks or unblocks deposits and withdrawals of a wallet.
+func (s *Service) SetWalletFrozen(ctx context.Context, id uuid.UUID, frozen bool) (*models.Wallet, error) {
+	wallet, err := s.db.SetWalletFrozen(ctx, id, frozen)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.SetWalletFrozen() err: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// SetWalletHot switches a wallet into or out of hot mode, in which deposits are accepted as
+// pending credits and applied to the balance in batches.
+func (s *Service) SetWalletHot(ctx context.Context, id uuid.UUID, hot bool) (*models.Wallet, error) {
+	wallet, err := s.db.SetWalletHot(ctx, id, hot)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.SetWalletHot() err: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// CloseWallet deletes a wallet whose balance is zero.
+func (s *Service) CloseWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	wallet, err := s.db.CloseWallet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.CloseWallet() err: %w", err)
+	}
+
+	return wallet, nil
+}
+
+func (s *Service) Deposit(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error) {
+	result, err := s.db.Deposit(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.Deposit() err: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *Service) ExecuteBatch(ctx context.Context, batch models.Batch) ([]models.BatchResult, error) {
+	if err := batch.Validate(); err != nil {
+		return nil, fmt.Errorf("batch.Validate() err: %w", err)
+	}
+
+	results, err := s.db.ExecuteBatch(ctx, batch)
 	if err != nil {
-		return fmt.Errorf("s.db.Withdraw() err: %w", err)
+		return nil, fmt.Errorf("s.db.ExecuteBatch() err: %w", err)
+	}
+
+	return results, nil
+}
+
+// Transfer moves funds from transfer.WalletID to transfer.TargetWalletID, both or none. It returns
+// the withdrawal from the source wallet followed by the deposit to the target wallet.
+func (s *Service) Transfer(ctx context.Context, transfer models.BatchOperation) ([]models.TransactionResult, error) {
+	transfer.OperationType = models.OperationTransfer
+
+	if err := transfer.Validate(); err != nil {
+		return nil, fmt.Errorf("transfer.Validate() err: %w", err)
+	}
+
+	results, err := s.db.ExecuteBatch(ctx, models.Batch{Atomic: true, Operations: []models.BatchOperation{transfer}})
+	if err != nil {
+		// The transfer is not presented as an operation of a batch, whose errors name its position.
+		var itemErr *models.BatchItemError
+		if errors.As(err, &itemErr) {
+			err = itemErr.Err
+		}
+
+		return nil, fmt.Errorf("s.db.ExecuteBatch() err: %w", err)
+	}
+
+	return results[0].Transactions, nil
+}
+
+// ImportWallets creates wallets with opening balances and their attributes, all or none. Wallets
+// without an ID get a new one, wallets without a creation time are created now and wallets
+// without a version start at the first one; wallets is updated in place. With dryRun nothing is
+// saved.
+func (s *Service) ImportWallets(ctx context.Context, wallets []models.Wallet, dryRun bool) error {
+	now := time.Now()
+	ids := make(map[uuid.UUID]struct{}, len(wallets))
+	externalRefs := make(map[string]struct{})
+
+	for i := range wallets {
+		w := &wallets[i]
+
+		if w.Balance < 0 {
+			return &models.BatchItemError{Index: i, Err: models.ErrBalanceBelowZero}
+		}
+
+		attrs := models.WalletAttributes{ExternalRef: &w.ExternalRef, Labels: &w.Labels, Metadata: w.Metadata}
+		if err := attrs.Validate(); err != nil {
+			return &models.BatchItemError{Index: i, Err: err}
+		}
+
+		if w.ExternalRef != "" {
+			if _, ok := externalRefs[w.ExternalRef]; ok {
+				return &models.BatchItemError{Index: i, Err: models.ErrExternalRefTaken}
+			}
+
+			externalRefs[w.ExternalRef] = struct{}{}
+		}
+
+		if w.ID == uuid.Nil {
+			w.ID = uuid.New()
+		}
+
+		if _, ok := ids[w.ID]; ok {
+			return &models.BatchItemError{Index: i, Err: models.ErrDuplicateWalletID}
+		}
+
+		ids[w.ID] = struct{}{}
+
+		if w.CreatedAt.IsZero() {
+			w.CreatedAt = now
+		}
+
+		w.UpdatedAt = w.CreatedAt
+		w.Version = max(w.Version, 1)
+
+		if w.Labels == nil {
+			w.Labels = []string{}
+		}
+
+		if w.Metadata == nil {
+			w.Metadata = json.RawMessage("{}")
+		}
+	}
+
+	if err := s.db.ImportWallets(ctx, wallets, dryRun); err != nil {
+		return fmt.Errorf("s.db.ImportWallets() err: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Service) Deposit(ctx context.Context, transaction models.Transaction) error {
-	err := s.db.Deposit(ctx, transaction)
+func (s *Service) ExportWallets(ctx context.Context, fn func(wallet models.Wallet) error) error {
+	if err := s.db.ExportWallets(ctx, fn); err != nil {
+		return fmt.Errorf("s.db.ExportWallets() err: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) ListTransactions(
+	ctx context.Context,
+	walletID uuid.UUID,
+	params models.HistoryParams,
+) (*models.TransactionsPage, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("params.Validate() err: %w", err)
+	}
+
+	if _, err := s.db.GetWallet(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("s.db.GetWallet(ctx, walletID) err: %w", err)
+	}
+
+	page, err := s.db.ListTransactions(ctx, models.TransactionFilter{WalletID: &walletID}, params)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.ListTransactions() err: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *Service) GetTransaction(ctx context.Context, id uuid.UUID) (*models.TransactionResult, error) {
+	transaction, err := s.db.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.GetTransaction(ctx, id) err: %w", err)
+	}
+
+	return transaction, nil
+}
+
+func (s *Service) SearchTransactions(
+	ctx context.Context,
+	filter models.TransactionFilter,
+	params models.HistoryParams,
+) (*models.TransactionsPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("filter.Validate() err: %w", err)
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("params.Validate() err: %w", err)
+	}
+
+	page, err := s.db.ListTransactions(ctx, filter, params)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.ListTransactions() err: %w", err)
+	}
+
+	return page, nil
+}
+
+func (s *Service) GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*models.BalanceAt, error) {
+	if _, err := s.db.GetWallet(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("s.db.GetWallet(ctx, walletID) err: %w", err)
+	}
+
+	balance, err := s.db.GetBalanceAt(ctx, walletID, at)
 	if err != nil {
-		return fmt.Errorf("s.db.Deposit() err: %w", err)
+		return nil, fmt.Errorf("s.db.GetBalanceAt() err: %w", err)
+	}
+
+	return &models.BalanceAt{
+		WalletID: walletID,
+		Balance:  balance,
+		At:       at,
+	}, nil
+}
+
+// VerifyChain checks that the history of a wallet was not edited after it was written.
+func (s *Service) VerifyChain(ctx context.Context, walletID uuid.UUID) (*models.ChainVerification, error) {
+	if _, err := s.db.GetWallet(ctx, walletID); err != nil {
+		return nil, fmt.Errorf("s.db.GetWallet(ctx, walletID) err: %w", err)
+	}
+
+	verification, err := s.db.VerifyChain(ctx, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("s.db.VerifyChain(ctx, walletID) err: %w", err)
+	}
+
+	return verification, nil
+}
+
+// WriteStatement streams the statement of a wallet to w. A zero period.From means the creation of
+// the wallet and a zero period.To means now.
+func (s *Service) WriteStatement(
+	ctx context.Context,
+	walletID uuid.UUID,
+	period models.StatementPeriod,
+	w models.StatementWriter,
+) error {
+	wallet, err := s.db.GetWallet(ctx, walletID)
+	if err != nil {
+		return fmt.Errorf("s.db.GetWallet(ctx, walletID) err: %w", err)
+	}
+
+	if period.From.IsZero() {
+		period.From = wallet.CreatedAt
+	}
+
+	if period.To.IsZero() {
+		period.To = time.Now()
+	}
+
+	if err := period.Validate(); err != nil {
+		return fmt.Errorf("period.Validate() err: %w", err)
+	}
+
+	if err := s.db.WriteStatement(ctx, walletID, period, w); err != nil {
+		return fmt.Errorf("s.db.WriteStatement() err: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,497 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgx/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const insertIdempotencyKeyQuery = `	INSERT INTO idempotency_keys
+										(key, operation_type, wallet_id, target_wallet_id, amount, created_at)
+									VALUES ($1, $2, $3, $4, $5, $6)
+									ON CONFLICT (key) DO NOTHING
+									RETURNING key`
+
+// balanceChange is one wallet balance change of a batch operation. A transfer has two.
+type balanceChange struct {
+	transaction models.Transaction
+	delta       float64
+}
+
+// ExecuteBatch executes the operations of the batch. An atomic batch runs in one database
+// transaction, which stops at the first failed operation and returns it as
+// *models.BatchItemError. In a best-effort batch every operation runs and is retried in a
+// transaction of its own, so a failed operation neither undoes nor repeats the others. With
+// expected versions in ctx an atomic batch only runs at those versions of the wallet of its first
+// operation, which is the source wallet of a transfer.
+func (p *Postgres) ExecuteBatch(ctx context.Context, batch models.Batch) ([]models.BatchResult, error) {
+	if batch.Atomic {
+		var results []models.BatchResult
+
+		err := p.runner.run(ctx, "batch", func(tx pgx.Tx) error {
+			if len(batch.Operations) > 0 {
+				if err := checkWalletVersion(ctx, tx, batch.Operations[0].WalletID); err != nil {
+					return err
+				}
+			}
+
+			var err error
+
+			results, err = executeOperations(ctx, tx, batch.Operations)
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return results, nil
+	}
+
+	results := make([]models.BatchResult, 0, len(batch.Operations))
+
+	for i, op := range batch.Operations {
+		var result models.BatchResult
+
+		err := p.runner.run(ctx, "batch operation", func(tx pgx.Tx) error {
+			executed, err := executeOperations(ctx, tx, []models.BatchOperation{op})
+			if err != nil {
+				return err
+			}
+
+			result = executed[0]
+
+			return nil
+		})
+
+		var itemErr *models.BatchItemError
+
+		switch {
+		case errors.As(err, &itemErr):
+			result = models.BatchResult{Err: itemErr.Err}
+		case err != nil:
+			// The database is not usable, so the operations not executed yet fail the same way.
+			log.Warnf("batch operation %d failed, skipping %d more: %v", i, len(batch.Operations)-i-1, err)
+
+			for j := i; j < len(batch.Operations); j++ {
+				results = append(results, models.BatchResult{Index: j, Err: err})
+			}
+
+			return results, nil
+		}
+
+		result.Index = i
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// executeOperations executes ops in tx and stops at the first failed operation, which it returns
+// as *models.BatchItemError. The operations are pipelined, so however many there are, they take
+// four round trips: one claims the idempotency keys, one finds the hot wallets among those
+// deposited to, one updates the wallets and looks up their chain links, and one writes the
+// history. Deposits to hot wallets are queued as pending credits one by one, as single deposits
+// are.
+func executeOperations(ctx context.Context, tx pgx.Tx, ops []models.BatchOperation) ([]models.BatchResult, error) {
+	executedAt := executionTimes(len(ops))
+	results := make([]models.BatchResult, len(ops))
+
+	replayed, err := claimIdempotencyKeys(ctx, tx, ops, executedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		results[i].Index = i
+
+		if !replayed[i] {
+			continue
+		}
+
+		transactions, err := replayBatchOperation(ctx, tx, op)
+		if err != nil {
+			return nil, batchOperationError(i, err)
+		}
+
+		results[i].Transactions = transactions
+		results[i].Replayed = true
+	}
+
+	pending, err := hotDeposits(ctx, tx, ops, replayed)
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := make([]bool, len(ops))
+
+	for i, op := range ops {
+		skipped[i] = replayed[i] || pending[i]
+
+		if !pending[i] {
+			continue
+		}
+
+		credit, err := queuePendingCredit(ctx, tx, models.Transaction{
+			WalletID:      op.WalletID,
+			Amount:        op.Amount,
+			OperationType: op.OperationType,
+		})
+		if err != nil {
+			return nil, batchOperationError(i, err)
+		}
+
+		results[i].Transactions = []models.TransactionResult{*credit}
+	}
+
+	balances, links, err := updateBatchBalances(ctx, tx, ops, skipped, executedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &pgx.Batch{}
+
+	for i, op := range ops {
+		if replayed[i] {
+			continue
+		}
+
+		// A pending credit keeps its ID once applied, which is when its key replays it.
+		if pending[i] {
+			if op.IdempotencyKey != "" {
+				batch.Queue(`UPDATE idempotency_keys SET transaction_ids = $2 WHERE key = $1`,
+					op.IdempotencyKey, []uuid.UUID{results[i].Transactions[0].TransactionID})
+			}
+
+			continue
+		}
+
+		changes := balanceChanges(op)
+		transactionIDs := make([]uuid.UUID, len(changes))
+
+		for j, c := range changes {
+			executed := newTransactionResult(c.transaction, balances[i][c.transaction.WalletID], executedAt[i])
+			transactionIDs[j] = executed.TransactionID
+
+			link := links[c.transaction.WalletID]
+			batch.Queue(insertTransactionQuery, insertTransactionArgs(executed, link)...)
+			links[c.transaction.WalletID] = link.next(executed)
+		}
+
+		if op.IdempotencyKey != "" {
+			batch.Queue(`UPDATE idempotency_keys SET transaction_ids = $2 WHERE key = $1`, op.IdempotencyKey, transactionIDs)
+		}
+	}
+
+	if batch.Len() == 0 {
+		return results, nil
+	}
+
+	err = readBatch(tx.SendBatch(ctx, batch), func(br pgx.BatchResults) error {
+		for i, op := range ops {
+			if replayed[i] {
+				continue
+			}
+
+			if !pending[i] {
+				results[i].Transactions = make([]models.TransactionResult, len(balanceChanges(op)))
+
+				for j := range results[i].Transactions {
+					if err := br.QueryRow().Scan(transactionDest(&results[i].Transactions[j])...); err != nil {
+						return fmt.Errorf("transaction writing to database err: %w", err)
+					}
+				}
+			}
+
+			if op.IdempotencyKey == "" {
+				continue
+			}
+
+			if _, err := br.Exec(); err != nil {
+				return fmt.Errorf("saving idempotency key transactions error: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// claimIdempotencyKeys saves the idempotency keys of ops and reports which operations must be
+// replayed because their key was saved before. A key saved by a concurrent transaction is claimed
+// once that transaction ends.
+func claimIdempotencyKeys(
+	ctx context.Context,
+	tx pgx.Tx,
+	ops []models.BatchOperation,
+	executedAt []time.Time,
+) ([]bool, error) {
+	replayed := make([]bool, len(ops))
+	batch := &pgx.Batch{}
+
+	for i, op := range ops {
+		if op.IdempotencyKey != "" {
+			batch.Queue(insertIdempotencyKeyQuery,
+				op.IdempotencyKey, op.OperationType, op.WalletID, targetWalletID(op), op.Amount, executedAt[i])
+		}
+	}
+
+	if batch.Len() == 0 {
+		return replayed, nil
+	}
+
+	err := readBatch(tx.SendBatch(ctx, batch), func(br pgx.BatchResults) error {
+		for i, op := range ops {
+			if op.IdempotencyKey == "" {
+				continue
+			}
+
+			var key string
+
+			err := br.QueryRow().Scan(&key)
+
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				replayed[i] = true
+			case err != nil:
+				return fmt.Errorf("saving idempotency key error: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replayed, nil
+}
+
+// updateBatchBalances applies the balance changes of the operations that are not skipped. It
+// returns the balances of the wallets after each operation and the last chain links of the
+// wallets before the batch.
+func updateBatchBalances(
+	ctx context.Context,
+	tx pgx.Tx,
+	ops []models.BatchOperation,
+	skipped []bool,
+	executedAt []time.Time,
+) ([]map[uuid.UUID]float64, map[uuid.UUID]chainLink, error) {
+	batch := &pgx.Batch{}
+	walletIDs := make([]uuid.UUID, 0)
+	links := make(map[uuid.UUID]chainLink)
+
+	for i, op := range ops {
+		if skipped[i] {
+			continue
+		}
+
+		for _, c := range lockOrder(balanceChanges(op)) {
+			batch.Queue(updateBalanceQuery, c.transaction.WalletID, c.delta, executedAt[i])
+
+			if _, ok := links[c.transaction.WalletID]; !ok {
+				links[c.transaction.WalletID] = chainLink{}
+				walletIDs = append(walletIDs, c.transaction.WalletID)
+			}
+		}
+	}
+
+	// The chain links are read after the updates, which lock the wallets until tx ends.
+	for _, walletID := range walletIDs {
+		batch.Queue(lastChainLinkQuery, walletID)
+	}
+
+	balances := make([]map[uuid.UUID]float64, len(ops))
+
+	if batch.Len() == 0 {
+		return balances, links, nil
+	}
+
+	err := readBatch(tx.SendBatch(ctx, batch), func(br pgx.BatchResults) error {
+		for i, op := range ops {
+			if skipped[i] {
+				continue
+			}
+
+			balances[i] = make(map[uuid.UUID]float64)
+
+			for _, c := range lockOrder(balanceChanges(op)) {
+				balance, err := scanBalanceUpdate(br.QueryRow())
+				if err != nil {
+					return batchOperationError(i, err)
+				}
+
+				balances[i][c.transaction.WalletID] = balance
+			}
+		}
+
+		for _, walletID := range walletIDs {
+			link, err := scanChainLink(br.QueryRow())
+			if err != nil {
+				return err
+			}
+
+			links[walletID] = link
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return balances, links, nil
+}
+
+// executionTimes returns the execution times of n operations in the order they run. The history
+// of a wallet is ordered by execution time, so the times increase by at least the microsecond
+// Postgres keeps of them.
+func executionTimes(n int) []time.Time {
+	times := make([]time.Time, n)
+
+	var last time.Time
+
+	for i := range times {
+		now := time.Now().Truncate(time.Microsecond)
+		if !now.After(last) {
+			now = last.Add(time.Microsecond)
+		}
+
+		times[i] = now
+		last = now
+	}
+
+	return times
+}
+
+// lockOrder orders the changes of an operation by wallet ID, so that transfers in opposite
+// directions do not deadlock.
+func lockOrder(changes []balanceChange) []balanceChange {
+	if len(changes) == 2 && bytes.Compare(changes[1].transaction.WalletID[:], changes[0].transaction.WalletID[:]) < 0 {
+		return []balanceChange{changes[1], changes[0]}
+	}
+
+	return changes
+}
+
+// batchOperationError returns err as the error of operation i when it concerns the operation
+// rather than the database.
+func batchOperationError(i int, err error) error {
+	if errors.Is(err, models.ErrWalletNotFound) ||
+		errors.Is(err, models.ErrWalletFrozen) ||
+		errors.Is(err, models.ErrBalanceBelowZero) ||
+		errors.Is(err, models.ErrIdempotencyKeyReused) {
+		return &models.BatchItemError{Index: i, Err: err}
+	}
+
+	return err
+}
+
+func balanceChanges(op models.BatchOperation) []balanceChange {
+	change := func(walletID uuid.UUID, operationType string, delta float64) balanceChange {
+		return balanceChange{
+			transaction: models.Transaction{WalletID: walletID, Amount: op.Amount, OperationType: operationType},
+			delta:       delta,
+		}
+	}
+
+	switch op.OperationType {
+	case models.OperationDeposit:
+		return []balanceChange{change(op.WalletID, models.OperationDeposit, op.Amount)}
+	case models.OperationWithdraw:
+		return []balanceChange{change(op.WalletID, models.OperationWithdraw, -op.Amount)}
+	default:
+		return []balanceChange{
+			change(op.WalletID, models.OperationWithdraw, -op.Amount),
+			change(op.TargetWalletID, models.OperationDeposit, op.Amount),
+		}
+	}
+}
+
+func targetWalletID(op models.BatchOperation) *uuid.UUID {
+	if op.TargetWalletID == uuid.Nil {
+		return nil
+	}
+
+	return &op.TargetWalletID
+}
+
+// readBatch passes the results of a sent batch to read and closes them. The first error wins.
+func readBatch(br pgx.BatchResults, read func(br pgx.BatchResults) error) error {
+	err := read(br)
+
+	if closeErr := br.Close(); err == nil && closeErr != nil {
+		return fmt.Errorf("sending batch err: %w", closeErr)
+	}
+
+	return err
+}
+
+// replayBatchOperation returns the transactions of an operation executed before with the same
+// idempotency key, provided the key was used for the same operation.
+func replayBatchOperation(ctx context.Context, tx pgx.Tx, op models.BatchOperation) ([]models.TransactionResult, error) {
+	var (
+		stored         models.BatchOperation
+		storedTargetID *uuid.UUID
+		transactionIDs []uuid.UUID
+	)
+
+	query := `	SELECT operation_type, wallet_id, target_wallet_id, amount, transaction_ids
+				FROM idempotency_keys
+				WHERE key = $1`
+
+	if err := tx.QueryRow(ctx, query, op.IdempotencyKey).Scan(
+		&stored.OperationType,
+		&stored.WalletID,
+		&storedTargetID,
+		&stored.Amount,
+		&transactionIDs,
+	); err != nil {
+		return nil, fmt.Errorf("getting idempotency key error: %w", err)
+	}
+
+	if storedTargetID != nil {
+		stored.TargetWalletID = *storedTargetID
+	}
+
+	if stored.OperationType != op.OperationType ||
+		stored.WalletID != op.WalletID ||
+		stored.TargetWalletID != op.TargetWalletID ||
+		stored.Amount != op.Amount {
+		return nil, models.ErrIdempotencyKeyReused
+	}
+
+	query = `	SELECT th.id, th.wallet_id, th.amount, th.transaction_type, th.executed_at, th.balance_after, th.reason
+				FROM unnest($1::uuid[]) WITH ORDINALITY AS k(id, n)
+				JOIN transactions_history th ON th.id = k.id
+				ORDER BY k.n`
+
+	rows, err := tx.Query(ctx, query, transactionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("getting replayed transactions error: %w", err)
+	}
+
+	transactions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.TransactionResult, error) {
+		var transaction models.TransactionResult
+
+		err := row.Scan(transactionDest(&transaction)...)
+
+		return transaction, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning replayed transactions error: %w", err)
+	}
+
+	return transactions, nil
+}
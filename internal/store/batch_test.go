@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// batchDB answers the queries of batch operations from wallet balances kept in memory, records the
+// pending credits of hot wallets and counts the transactions and round trips.
+type batchDB struct {
+	balances   map[uuid.UUID]float64
+	hot        map[uuid.UUID]bool
+	credits    map[uuid.UUID]float64
+	begun      int
+	roundTrips int
+}
+
+func (db *batchDB) BeginTx(_ context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	db.begun++
+
+	return &batchTx{db: db}, nil
+}
+
+type batchTx struct {
+	pgx.Tx
+	db *batchDB
+}
+
+func (tx *batchTx) Commit(_ context.Context) error {
+	return nil
+}
+
+func (tx *batchTx) Rollback(_ context.Context) error {
+	return pgx.ErrTxClosed
+}
+
+// QueryRow answers the hot wallets query, the lock of a hot wallet, which is never frozen, and the
+// wallet version query, for which every wallet is at version 1.
+func (tx *batchTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	tx.db.roundTrips++
+
+	switch {
+	case sql == hotWalletsQuery:
+		walletIDs, _ := args[0].([]uuid.UUID)
+		hot := make([]uuid.UUID, 0)
+
+		for _, walletID := range walletIDs {
+			if tx.db.hot[walletID] {
+				hot = append(hot, walletID)
+			}
+		}
+
+		return fakeRow{values: []any{hot}}
+	case strings.Contains(sql, "FOR SHARE"):
+		return fakeRow{values: []any{false}}
+	}
+
+	return fakeRow{values: []any{int64(1)}}
+}
+
+// Exec queues a pending credit.
+func (tx *batchTx) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	tx.db.roundTrips++
+
+	walletID, _ := args[1].(uuid.UUID)
+	amount, _ := args[2].(float64)
+	tx.db.credits[walletID] += amount
+
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (tx *batchTx) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	tx.db.roundTrips++
+
+	rows := make([]fakeRow, 0, b.Len())
+
+	for _, q := range b.QueuedQueries {
+		rows = append(rows, tx.db.answer(q.SQL, q.Arguments))
+	}
+
+	return &fakeBatchResults{rows: rows}
+}
+
+func (db *batchDB) answer(sql string, args []any) fakeRow {
+	switch sql {
+	case updateBalanceQuery:
+		walletID, _ := args[0].(uuid.UUID)
+		delta, _ := args[1].(float64)
+
+		balance, ok := db.balances[walletID]
+
+		switch {
+		case !ok:
+			return fakeRow{err: pgx.ErrNoRows}
+		case balance+delta < 0:
+			return fakeRow{err: &pgconn.PgError{Code: pgerrcode.CheckViolation}}
+		}
+
+		db.balances[walletID] = balance + delta
+
+		return fakeRow{values: []any{balance + delta, false}}
+	case lastChainLinkQuery:
+		return fakeRow{err: pgx.ErrNoRows}
+	case insertTransactionQuery:
+		return fakeRow{values: args[:7]}
+	}
+
+	return fakeRow{}
+}
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.values[i]))
+	}
+
+	return nil
+}
+
+type fakeBatchResults struct {
+	pgx.BatchResults
+	rows []fakeRow
+}
+
+func (br *fakeBatchResults) QueryRow() pgx.Row {
+	row := br.rows[0]
+	br.rows = br.rows[1:]
+
+	return row
+}
+
+func (br *fakeBatchResults) Close() error {
+	return nil
+}
+
+func TestExecuteBatch(t *testing.T) {
+	source, target, hot, unknown := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	newDB := func() (*batchDB, *Postgres) {
+		db := &batchDB{
+			balances: map[uuid.UUID]float64{source: 100, target: 0, hot: 0},
+			hot:      map[uuid.UUID]bool{hot: true},
+			credits:  make(map[uuid.UUID]float64),
+		}
+
+		return db, &Postgres{runner: newTestRunner(db, 0)}
+	}
+
+	deposit := models.BatchOperation{OperationType: models.OperationDeposit, WalletID: source, Amount: 1}
+
+	t.Run("atomic batches are pipelined", func(t *testing.T) {
+		db, p := newDB()
+
+		ops := make([]models.BatchOperation, 0, 100)
+		for range 100 {
+			ops = append(ops, deposit)
+		}
+
+		ops = append(ops, models.BatchOperation{OperationType: models.OperationTransfer, WalletID: source, TargetWalletID: target, Amount: 50})
+
+		results, err := p.ExecuteBatch(context.Background(), models.Batch{Atomic: true, Operations: ops})
+		require.NoError(t, err)
+		require.Len(t, results, 101)
+		require.Equal(t, 1, db.begun)
+		require.Equal(t, 3, db.roundTrips)
+
+		// The history of a wallet is ordered by execution time, which follows the operations.
+		for i := 1; i < len(results); i++ {
+			require.True(t, results[i].Transactions[0].ExecutedAt.After(results[i-1].Transactions[0].ExecutedAt))
+		}
+
+		transfer := results[100].Transactions
+		require.Len(t, transfer, 2)
+		require.Equal(t, 150.0, transfer[0].BalanceAfter)
+		require.Equal(t, 50.0, transfer[1].BalanceAfter)
+	})
+
+	t.Run("deposits to hot wallets are queued", func(t *testing.T) {
+		db, p := newDB()
+
+		results, err := p.ExecuteBatch(context.Background(), models.Batch{Atomic: true, Operations: []models.BatchOperation{
+			{OperationType: models.OperationDeposit, WalletID: hot, Amount: 5},
+			deposit,
+			{OperationType: models.OperationTransfer, WalletID: source, TargetWalletID: hot, Amount: 10},
+		}})
+		require.NoError(t, err)
+
+		require.True(t, results[0].Transactions[0].Pending)
+		require.Equal(t, hot, results[0].Transactions[0].WalletID)
+		require.Equal(t, 101.0, results[1].Transactions[0].BalanceAfter)
+		require.False(t, results[1].Transactions[0].Pending)
+
+		// Only deposits are queued, as they are outside of batches.
+		require.Equal(t, 10.0, results[2].Transactions[1].BalanceAfter)
+		require.Equal(t, map[uuid.UUID]float64{hot: 5}, db.credits)
+		require.Equal(t, 10.0, db.balances[hot])
+	})
+
+	t.Run("atomic batches stop at the first failed operation", func(t *testing.T) {
+		_, p := newDB()
+
+		_, err := p.ExecuteBatch(context.Background(), models.Batch{Atomic: true, Operations: []models.BatchOperation{
+			deposit,
+			{OperationType: models.OperationWithdraw, WalletID: target, Amount: 1},
+		}})
+
+		var itemErr *models.BatchItemError
+		require.ErrorAs(t, err, &itemErr)
+		require.Equal(t, 1, itemErr.Index)
+		require.ErrorIs(t, err, models.ErrBalanceBelowZero)
+	})
+
+	t.Run("atomic batches check the version of the first wallet", func(t *testing.T) {
+		db, p := newDB()
+
+		ctx := models.WithExpectedVersions(context.Background(), []int64{2})
+
+		_, err := p.ExecuteBatch(ctx, models.Batch{Atomic: true, Operations: []models.BatchOperation{deposit}})
+		require.ErrorIs(t, err, models.ErrWalletVersionMismatch)
+		require.Equal(t, 100.0, db.balances[source])
+
+		ctx = models.WithExpectedVersions(context.Background(), []int64{1})
+
+		_, err = p.ExecuteBatch(ctx, models.Batch{Atomic: true, Operations: []models.BatchOperation{deposit}})
+		require.NoError(t, err)
+	})
+
+	t.Run("best-effort operations run in transactions of their own", func(t *testing.T) {
+		db, p := newDB()
+
+		results, err := p.ExecuteBatch(context.Background(), models.Batch{Operations: []models.BatchOperation{
+			deposit,
+			{OperationType: models.OperationDeposit, WalletID: unknown, Amount: 1},
+			deposit,
+		}})
+		require.NoError(t, err)
+		require.Equal(t, 3, db.begun)
+
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, models.ErrWalletNotFound)
+		require.Equal(t, 1, results[1].Index)
+		require.NoError(t, results[2].Err)
+		require.Equal(t, 102.0, results[2].Transactions[0].BalanceAfter)
+	})
+}
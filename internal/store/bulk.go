@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	log "github.com/sirupsen/logrus"
+)
+
+// ImportWallets creates the wallets with COPY in one database transaction and records an
+// OPENING_BALANCE history entry for every wallet with a non-zero balance. With dryRun the
+// transaction is rolled back after all rows were written, so only database constraints are checked.
+func (p *Postgres) ImportWallets(ctx context.Context, wallets []models.Wallet, dryRun bool) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("p.db.Begin(ctx) err: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Warnf("import tx.Rollback(ctx) err: %v", err)
+		}
+	}()
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"wallets"},
+		[]string{"id", "balance", "created_at", "updated_at", "deleted", "frozen", "hot", "version", "external_ref", "labels", "metadata"},
+		pgx.CopyFromSlice(len(wallets), func(i int) ([]any, error) {
+			w := wallets[i]
+
+			return []any{
+				w.ID, w.Balance, w.CreatedAt, w.CreatedAt, false, w.Frozen, w.Hot, w.Version, w.ExternalRef, w.Labels, string(w.Metadata),
+			}, nil
+		}),
+	)
+
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.As(err, &pgErr) && pgErr.ConstraintName == "idx_wallets_external_ref":
+		return fmt.Errorf("copying wallets error: %w: %s", models.ErrExternalRefTaken, pgErr.Detail)
+	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation:
+		return fmt.Errorf("copying wallets error: %w: %s", models.ErrDuplicateWalletID, pgErr.Detail)
+	case err != nil:
+		return fmt.Errorf("copying wallets error: %w", err)
+	}
+
+	openings := make([][]any, 0, len(wallets))
+
+	for _, w := range wallets {
+		if w.Balance == 0 {
+			continue
+		}
+
+		opening := newTransactionResult(models.Transaction{
+			WalletID:      w.ID,
+			Amount:        w.Balance,
+			OperationType: models.OperationOpeningBalance,
+		}, w.Balance, w.CreatedAt)
+
+		openings = append(openings, insertTransactionArgs(opening, chainLink{}))
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"transactions_history"},
+		[]string{"id", "wallet_id", "amount", "transaction_type", "executed_at", "balance_after", "reason", "seq", "prev_hash", "hash"},
+		pgx.CopyFromRows(openings),
+	)
+	if err != nil {
+		return fmt.Errorf("copying opening balances error: %w", err)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("transaction commit err: %w", err)
+	}
+
+	return nil
+}
+
+// ExportWallets passes every wallet to fn, oldest first.
+func (p *Postgres) ExportWallets(ctx context.Context, fn func(wallet models.Wallet) error) error {
+	query := `	SELECT id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata
+				FROM wallets
+				WHERE deleted = false
+				ORDER BY created_at, id`
+
+	rows, err := p.db.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("listing wallets error: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var wallet models.Wallet
+
+		if err := rows.Scan(walletDest(&wallet)...); err != nil {
+			return fmt.Errorf("scanning wallet error: %w", err)
+		}
+
+		if err := fn(wallet); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows.Err(): %w", err)
+	}
+
+	return nil
+}
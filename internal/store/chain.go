@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+// VerifyChain walks the hash chain of the wallet's history from its first transaction and stops
+// at the first link that does not hold.
+func (p *Postgres) VerifyChain(ctx context.Context, walletID uuid.UUID) (*models.ChainVerification, error) {
+	query := `	SELECT id, wallet_id, amount, transaction_type, executed_at, balance_after, reason, prev_hash, hash
+				FROM transactions_history
+				WHERE wallet_id = $1
+				ORDER BY seq`
+
+	rows, err := p.db.Query(ctx, query, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("listing chain error: %w", err)
+	}
+
+	defer rows.Close()
+
+	var (
+		result = &models.ChainVerification{WalletID: walletID, Valid: true}
+		prev   []byte
+		linked bool
+	)
+
+	for rows.Next() {
+		var (
+			transaction    models.TransactionResult
+			prevHash, hash []byte
+		)
+
+		if err := rows.Scan(append(transactionDest(&transaction), &prevHash, &hash)...); err != nil {
+			return nil, fmt.Errorf("scanning chain link error: %w", err)
+		}
+
+		var reason string
+
+		switch {
+		case hash == nil && !linked:
+			result.Unchained++
+
+			continue
+		case hash == nil:
+			reason = models.ChainBreakMissingHash
+		case !bytes.Equal(prevHash, prev):
+			reason = models.ChainBreakPrevMismatch
+		case !bytes.Equal(hash, transaction.ChainHash(prevHash)):
+			reason = models.ChainBreakHashMismatch
+		}
+
+		if reason != "" {
+			result.Valid = false
+			result.BrokenLink = &models.ChainBreak{
+				TransactionID: transaction.TransactionID,
+				ExecutedAt:    transaction.ExecutedAt,
+				Reason:        reason,
+			}
+
+			return result, nil
+		}
+
+		linked = true
+		prev = hash
+		result.Verified++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err(): %w", err)
+	}
+
+	return result, nil
+}
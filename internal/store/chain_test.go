@@ -0,0 +1,31 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainLinkNext(t *testing.T) {
+	executedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	first := newTransactionResult(models.Transaction{WalletID: uuid.New(), Amount: 1}, 1, executedAt)
+	// A host with a clock behind the previous one executes the next transaction "earlier".
+	second := newTransactionResult(models.Transaction{WalletID: first.WalletID, Amount: 2}, 3, executedAt.Add(-time.Minute))
+
+	link := chainLink{}.next(first)
+	require.Equal(t, int64(1), link.seq)
+	require.Equal(t, first.ChainHash(nil), link.hash)
+
+	next := link.next(second)
+	require.Equal(t, int64(2), next.seq)
+	require.Equal(t, second.ChainHash(link.hash), next.hash)
+
+	args := insertTransactionArgs(second, link)
+	require.Equal(t, int64(2), args[7])
+	require.Equal(t, link.hash, args[8])
+	require.Equal(t, next.hash, args[9])
+}
@@ -0,0 +1,300 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgx/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetWalletHot switches a wallet into or out of hot mode. Deposits to a hot wallet are queued as
+// pending credits, which ApplyPendingCredits adds to the balance in batches, so that concurrent
+// deposits do not wait for each other's row lock.
+func (p *Postgres) SetWalletHot(ctx context.Context, id uuid.UUID, hot bool) (*models.Wallet, error) {
+	var wallet models.Wallet
+
+	query := `	UPDATE wallets SET hot = $2, updated_at = $3, version = version + 1
+				WHERE id = $1 AND deleted = false
+				RETURNING id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata`
+
+	err := p.db.QueryRow(ctx, query, id, hot, time.Now()).Scan(walletDest(&wallet)...)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, models.ErrWalletNotFound
+	case err != nil:
+		return nil, fmt.Errorf("updating wallet hot error: %w", err)
+	}
+
+	return &wallet, nil
+}
+
+// walletHot reports whether deposits to the wallet are queued as pending credits. The row is not
+// locked, as deposits to a hot wallet must not wait for each other.
+func walletHot(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (bool, error) {
+	var hot bool
+
+	query := `	SELECT hot
+				FROM wallets
+				WHERE id = $1 AND deleted = false`
+
+	err := tx.QueryRow(ctx, query, walletID).Scan(&hot)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return false, models.ErrWalletNotFound
+	case err != nil:
+		return false, fmt.Errorf("getting wallet mode error: %w", err)
+	}
+
+	return hot, nil
+}
+
+const hotWalletsQuery = `	SELECT COALESCE(array_agg(id), '{}')
+							FROM wallets
+							WHERE id = ANY($1) AND hot = true AND deleted = false`
+
+// hotDeposits reports which of the deposits among ops, other than the skipped operations, are to
+// hot wallets and must be queued as pending credits.
+func hotDeposits(ctx context.Context, tx pgx.Tx, ops []models.BatchOperation, skipped []bool) ([]bool, error) {
+	hot := make([]bool, len(ops))
+	walletIDs := make([]uuid.UUID, 0)
+
+	for i, op := range ops {
+		if !skipped[i] && op.OperationType == models.OperationDeposit {
+			walletIDs = append(walletIDs, op.WalletID)
+		}
+	}
+
+	if len(walletIDs) == 0 {
+		return hot, nil
+	}
+
+	var hotWalletIDs []uuid.UUID
+
+	if err := tx.QueryRow(ctx, hotWalletsQuery, walletIDs).Scan(&hotWalletIDs); err != nil {
+		return nil, fmt.Errorf("getting hot wallets error: %w", err)
+	}
+
+	for i, op := range ops {
+		hot[i] = !skipped[i] && op.OperationType == models.OperationDeposit && slices.Contains(hotWalletIDs, op.WalletID)
+	}
+
+	return hot, nil
+}
+
+// queuePendingCredit records a deposit to a hot wallet without changing its balance. The share
+// lock does not block other deposits, only a wallet update, so the wallet cannot be closed or
+// frozen while the credit is queued.
+func queuePendingCredit(ctx context.Context, tx pgx.Tx, transaction models.Transaction) (*models.TransactionResult, error) {
+	var frozen bool
+
+	query := `	SELECT frozen
+				FROM wallets
+				WHERE id = $1 AND deleted = false
+				FOR SHARE`
+
+	err := tx.QueryRow(ctx, query, transaction.WalletID).Scan(&frozen)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, models.ErrWalletNotFound
+	case err != nil:
+		return nil, fmt.Errorf("getting hot wallet error: %w", err)
+	case frozen:
+		return nil, models.ErrWalletFrozen
+	}
+
+	credit := newTransactionResult(transaction, 0, time.Now())
+	credit.Pending = true
+
+	query = `	INSERT INTO pending_credits (id, wallet_id, amount, reason, created_at)
+				VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.Exec(ctx, query,
+		credit.TransactionID, credit.WalletID, credit.Amount, credit.Reason, credit.ExecutedAt,
+	); err != nil {
+		return nil, fmt.Errorf("queuing pending credit error: %w", err)
+	}
+
+	return credit, nil
+}
+
+// ApplyPendingCredits adds pending credits to the balances of their wallets, at most limit
+// credits per wallet, and returns how many were applied. Every credit becomes a deposit in the
+// wallet history under the ID it was accepted with. Credits of frozen wallets stay pending. A
+// wallet whose credits fail to apply does not hold up the other wallets; its error is logged and
+// returned together with the errors of the other failed wallets.
+func (p *Postgres) ApplyPendingCredits(ctx context.Context, limit int) (int, error) {
+	rows, err := p.db.Query(ctx, `SELECT DISTINCT wallet_id FROM pending_credits`)
+	if err != nil {
+		return 0, fmt.Errorf("listing wallets with pending credits error: %w", err)
+	}
+
+	walletIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, fmt.Errorf("scanning wallets with pending credits error: %w", err)
+	}
+
+	return p.applyPendingCreditsOf(ctx, walletIDs, limit)
+}
+
+func (p *Postgres) applyPendingCreditsOf(ctx context.Context, walletIDs []uuid.UUID, limit int) (int, error) {
+	var (
+		applied int
+		errs    []error
+	)
+
+	for _, walletID := range walletIDs {
+		n, err := p.applyWalletPendingCredits(ctx, walletID, limit)
+		if err != nil {
+			log.Warnf("applying pending credits of wallet %s failed: %v", walletID, err)
+
+			errs = append(errs, fmt.Errorf("applying pending credits of wallet %s: %w", walletID, err))
+
+			continue
+		}
+
+		applied += n
+	}
+
+	return applied, errors.Join(errs...)
+}
+
+func (p *Postgres) applyWalletPendingCredits(ctx context.Context, walletID uuid.UUID, limit int) (int, error) {
+	applied := 0
+
+	err := p.runner.run(ctx, "pending_credits", func(tx pgx.Tx) error {
+		var err error
+
+		applied, err = applyPendingCreditsTx(ctx, tx, walletID, limit)
+
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return applied, nil
+}
+
+func applyPendingCreditsTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, limit int) (int, error) {
+	var frozen bool
+
+	query := `	SELECT frozen
+				FROM wallets
+				WHERE id = $1 AND deleted = false
+				FOR UPDATE`
+
+	err := tx.QueryRow(ctx, query, walletID).Scan(&frozen)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		log.Warnf("pending credits of wallet %s are kept: the wallet is closed", walletID)
+
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("locking wallet error: %w", err)
+	case frozen:
+		return 0, nil
+	}
+
+	query = `	DELETE FROM pending_credits
+				WHERE id IN (
+					SELECT id
+					FROM pending_credits
+					WHERE wallet_id = $1
+					ORDER BY created_at, id
+					LIMIT $2
+				)
+				RETURNING id, wallet_id, amount, reason`
+
+	rows, err := tx.Query(ctx, query, walletID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("taking pending credits error: %w", err)
+	}
+
+	credits, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Transaction, error) {
+		credit := models.Transaction{OperationType: models.OperationDeposit}
+
+		err := row.Scan(&credit.TransactionID, &credit.WalletID, &credit.Amount, &credit.Reason)
+
+		return credit, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("scanning pending credits error: %w", err)
+	}
+
+	if len(credits) == 0 {
+		return 0, nil
+	}
+
+	// The credits share the execution time, so the history orders them by ID. They are applied in
+	// that order for the running balances and the hash chain to follow it.
+	slices.SortFunc(credits, func(a, b models.Transaction) int {
+		return bytes.Compare(a.TransactionID[:], b.TransactionID[:])
+	})
+
+	executedAt := time.Now()
+	balances := make([]float64, len(credits))
+
+	var prev chainLink
+
+	// Each credit is added on its own, so the running balances are computed by Postgres as exactly
+	// as the balance itself.
+	batch := &pgx.Batch{}
+
+	for _, credit := range credits {
+		batch.Queue(updateBalanceQuery, walletID, credit.Amount, executedAt)
+	}
+
+	batch.Queue(lastChainLinkQuery, walletID)
+
+	err = readBatch(tx.SendBatch(ctx, batch), func(br pgx.BatchResults) error {
+		for i := range credits {
+			if balances[i], err = scanBalanceUpdate(br.QueryRow()); err != nil {
+				return err
+			}
+		}
+
+		prev, err = scanChainLink(br.QueryRow())
+
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	batch = &pgx.Batch{}
+
+	for i, credit := range credits {
+		executed := newTransactionResult(credit, balances[i], executedAt)
+		executed.TransactionID = credit.TransactionID
+
+		batch.Queue(insertTransactionQuery, insertTransactionArgs(executed, prev)...)
+
+		prev = prev.next(executed)
+	}
+
+	err = readBatch(tx.SendBatch(ctx, batch), func(br pgx.BatchResults) error {
+		for range credits {
+			if _, err := br.Exec(); err != nil {
+				return fmt.Errorf("transaction writing to database err: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(credits), nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// creditsDB answers the wallet lock of applying pending credits with the row of the wallet in
+// rows and records the wallets that were locked.
+type creditsDB struct {
+	rows   map[uuid.UUID]fakeRow
+	locked []uuid.UUID
+}
+
+func (db *creditsDB) BeginTx(_ context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return &creditsTx{db: db}, nil
+}
+
+type creditsTx struct {
+	fakeTx
+	db *creditsDB
+}
+
+func (tx *creditsTx) QueryRow(_ context.Context, _ string, args ...any) pgx.Row {
+	walletID, _ := args[0].(uuid.UUID)
+	tx.db.locked = append(tx.db.locked, walletID)
+
+	return tx.db.rows[walletID]
+}
+
+func TestApplyPendingCreditsOf(t *testing.T) {
+	failing, frozen := uuid.New(), uuid.New()
+	errLock := errors.New("connection reset")
+
+	db := &creditsDB{rows: map[uuid.UUID]fakeRow{
+		failing: {err: errLock},
+		frozen:  {values: []any{true}},
+	}}
+	p := &Postgres{runner: newTestRunner(db, 0)}
+
+	applied, err := p.applyPendingCreditsOf(context.Background(), []uuid.UUID{failing, frozen}, 100)
+	require.ErrorIs(t, err, errLock)
+	require.ErrorContains(t, err, failing.String())
+	require.Zero(t, applied)
+	require.Equal(t, []uuid.UUID{failing, frozen}, db.locked)
+}
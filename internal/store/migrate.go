@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+var (
+	ErrMigrationsPending    = errors.New("database migrations are pending")
+	ErrDestructiveMigration = errors.New("migration drops tables or columns")
+)
+
+// migrationLockID is the key of the Postgres advisory lock held while migrations are applied, so
+// that replicas starting together do not migrate concurrently.
+const migrationLockID = 4_102_019_170
+
+// destructiveStatement matches the statements that lose data when a migration is rolled back.
+//
+//nolint:gochecknoglobals
+var destructiveStatement = regexp.MustCompile(`(?i)\b(DROP\s+(TABLE|COLUMN|SCHEMA)|TRUNCATE|DELETE\s+FROM)\b`)
+
+type MigrateOptions struct {
+	// Max is the number of migrations to apply at most. Zero applies all of them.
+	Max int
+	// AllowDestructive permits migrations that drop tables or columns together with their data.
+	AllowDestructive bool
+}
+
+// PlannedMigration is a migration that would be applied, with the SQL it would run.
+type PlannedMigration struct {
+	ID          string   `json:"id"`
+	Queries     []string `json:"queries"`
+	Destructive bool     `json:"destructive"`
+}
+
+// MigrationState is an embedded migration and the time it was applied, if it was.
+type MigrationState struct {
+	ID        string     `json:"id"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// Migrate applies all migrations in the given direction. Destructive migrations are refused.
+func (p *Postgres) Migrate(direction migrate.MigrationDirection) error {
+	if _, err := p.ApplyMigrations(context.Background(), direction, MigrateOptions{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ApplyMigrations applies migrations in the given direction and returns how many were applied.
+// Unless opts allows it, nothing is applied when one of the planned migrations is destructive.
+func (p *Postgres) ApplyMigrations(
+	ctx context.Context,
+	direction migrate.MigrationDirection,
+	opts MigrateOptions,
+) (int, error) {
+	db, err := p.lockMigrations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer closeSQLConn(db)
+
+	if !opts.AllowDestructive {
+		plan, err := planMigrations(db, direction, opts.Max)
+		if err != nil {
+			return 0, err
+		}
+
+		destructive := make([]string, 0)
+
+		for _, m := range plan {
+			if m.Destructive {
+				destructive = append(destructive, m.ID)
+			}
+		}
+
+		if len(destructive) > 0 {
+			return 0, fmt.Errorf("%w: %s", ErrDestructiveMigration, strings.Join(destructive, ", "))
+		}
+	}
+
+	applied, err := migrate.ExecMaxContext(ctx, db, "postgres", migrationSource(), direction, opts.Max)
+	if err != nil {
+		return applied, fmt.Errorf("migrate.ExecMaxContext(...): %w", err)
+	}
+
+	return applied, nil
+}
+
+// PlanMigrations lists the migrations ApplyMigrations would apply, without applying them.
+func (p *Postgres) PlanMigrations(direction migrate.MigrationDirection, maxMigrations int) ([]PlannedMigration, error) {
+	conn := stdlib.OpenDBFromPool(p.db)
+
+	defer closeSQLConn(conn)
+
+	return planMigrations(conn, direction, maxMigrations)
+}
+
+func planMigrations(db *sql.DB, direction migrate.MigrationDirection, maxMigrations int) ([]PlannedMigration, error) {
+	planned, _, err := migrate.PlanMigration(db, "postgres", migrationSource(), direction, maxMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("migrate.PlanMigration(...): %w", err)
+	}
+
+	plan := make([]PlannedMigration, 0, len(planned))
+
+	for _, m := range planned {
+		plan = append(plan, PlannedMigration{
+			ID:          m.Id,
+			Queries:     m.Queries,
+			Destructive: isDestructive(m.Queries),
+		})
+	}
+
+	return plan, nil
+}
+
+func isDestructive(queries []string) bool {
+	for _, query := range queries {
+		if destructiveStatement.MatchString(query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lockMigrations waits for the migration lock and returns the database to migrate through, which
+// releases the lock when it is closed. The database has a single connection of its own outside the
+// pool, which takes the lock whenever it connects, so migrations always run on the connection
+// holding the lock and do not compete with the pool for connections, however small it is.
+func (p *Postgres) lockMigrations(ctx context.Context) (*sql.DB, error) {
+	db := stdlib.OpenDB(*p.db.Config().ConnConfig, stdlib.OptionAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		// No statement timeout for the session, which waits for another replica to finish migrating
+		// and then runs the migrations.
+		if _, err := conn.Exec(ctx, "SET statement_timeout = 0"); err != nil {
+			return fmt.Errorf("lifting statement timeout: %w", err)
+		}
+
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return fmt.Errorf("taking migration lock: %w", err)
+		}
+
+		return nil
+	}))
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	// Connecting now takes the lock while ctx can still cancel the wait.
+	if err := db.PingContext(ctx); err != nil {
+		closeSQLConn(db)
+
+		return nil, fmt.Errorf("db.PingContext(ctx): %w", err)
+	}
+
+	return db, nil
+}
+
+// MigrationStatus lists the embedded migrations in the order they are applied.
+func (p *Postgres) MigrationStatus() ([]MigrationState, error) {
+	conn := stdlib.OpenDBFromPool(p.db)
+
+	defer closeSQLConn(conn)
+
+	found, err := migrationSource().FindMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("FindMigrations(): %w", err)
+	}
+
+	records, err := migrate.GetMigrationRecords(conn, "postgres")
+	if err != nil {
+		return nil, fmt.Errorf("migrate.GetMigrationRecords(...): %w", err)
+	}
+
+	applied := make(map[string]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+
+	states := make([]MigrationState, 0, len(found))
+
+	for _, m := range found {
+		state := MigrationState{ID: m.Id}
+		if at, ok := applied[m.Id]; ok {
+			state.AppliedAt = &at
+		}
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// CheckMigrations returns an error if the database schema is not at the version
+// of the latest embedded migration.
+func (p *Postgres) CheckMigrations(ctx context.Context) error {
+	found, err := migrationSource().FindMigrations()
+	if err != nil {
+		return fmt.Errorf("FindMigrations(): %w", err)
+	}
+
+	rows, err := p.db.Query(ctx, "SELECT id FROM gorp_migrations")
+	if err != nil {
+		return fmt.Errorf("listing applied migrations error: %w", err)
+	}
+
+	applied, err := pgx.CollectRows(rows, pgx.RowTo[string])
+
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UndefinedTable:
+		return fmt.Errorf("%w: %d pending", ErrMigrationsPending, len(found))
+	case err != nil:
+		return fmt.Errorf("listing applied migrations error: %w", err)
+	}
+
+	if pending := pendingMigrations(found, applied); pending > 0 {
+		return fmt.Errorf("%w: %d pending", ErrMigrationsPending, pending)
+	}
+
+	return nil
+}
+
+// pendingMigrations counts the migrations of found that are not applied.
+func pendingMigrations(found []*migrate.Migration, applied []string) int {
+	done := make(map[string]struct{}, len(applied))
+	for _, id := range applied {
+		done[id] = struct{}{}
+	}
+
+	pending := 0
+
+	for _, m := range found {
+		if _, ok := done[m.Id]; !ok {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+func migrationSource() migrate.MigrationSource {
+	assetDir := func() func(string) ([]string, error) {
+		return func(path string) ([]string, error) {
+			dirEntry, err := migrations.ReadDir(path)
+			if err != nil {
+				return nil, fmt.Errorf("migrations.ReadDir(): %w", err)
+			}
+
+			entries := make([]string, 0)
+
+			for _, e := range dirEntry {
+				entries = append(entries, e.Name())
+			}
+
+			return entries, nil
+		}
+	}()
+
+	return migrate.AssetMigrationSource{
+		Asset:    migrations.ReadFile,
+		AssetDir: assetDir,
+		Dir:      "migrations",
+	}
+}
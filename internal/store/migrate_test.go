@@ -0,0 +1,57 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestructiveDownMigrations(t *testing.T) {
+	found, err := migrationSource().FindMigrations()
+	require.NoError(t, err)
+
+	down := make(map[string][]string, len(found))
+	for _, m := range found {
+		require.False(t, isDestructive(m.Up), m.Id)
+
+		down[m.Id] = m.Down
+	}
+
+	require.True(t, isDestructive(down["20241209120000_initial_script.sql"]))
+	require.True(t, isDestructive(down["20261019130000_transactions_balance_after.sql"]))
+	require.True(t, isDestructive(down["20261019160000_idempotency_keys.sql"]))
+	require.False(t, isDestructive(down["20261019120000_transactions_search.sql"]))
+}
+
+func TestIsDestructive(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "drop table wallets;", want: true},
+		{query: "ALTER TABLE wallets\n\tDROP   COLUMN frozen;", want: true},
+		{query: "TRUNCATE wallets;", want: true},
+		{query: "DELETE FROM idempotency_keys;", want: true},
+		{query: "DROP INDEX idx_balance;", want: false},
+		{query: "ALTER TABLE wallets ADD COLUMN dropped_at timestamp;", want: false},
+		{query: "UPDATE wallets SET deleted_from = 'x';", want: false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, isDestructive([]string{tt.query}), tt.query)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	found, err := migrationSource().FindMigrations()
+	require.NoError(t, err)
+
+	applied := make([]string, 0, len(found))
+	for _, m := range found {
+		applied = append(applied, m.Id)
+	}
+
+	require.Zero(t, pendingMigrations(found, applied))
+	require.Equal(t, 1, pendingMigrations(found, applied[:len(applied)-1]))
+	require.Equal(t, len(found), pendingMigrations(found, nil))
+}
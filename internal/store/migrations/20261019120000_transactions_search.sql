@@ -0,0 +1,9 @@
+-- +migrate Up
+
+CREATE INDEX idx_transactions_history_executed_at_id ON transactions_history (executed_at DESC, id DESC);
+CREATE INDEX idx_transactions_history_wallet_id_executed_at_id ON transactions_history (wallet_id, executed_at DESC, id DESC);
+
+-- +migrate Down
+
+DROP INDEX idx_transactions_history_wallet_id_executed_at_id;
+DROP INDEX idx_transactions_history_executed_at_id;
@@ -0,0 +1,19 @@
+-- +migrate Up
+
+ALTER TABLE transactions_history ADD COLUMN balance_after numeric;
+
+UPDATE transactions_history th
+SET balance_after = running.balance
+FROM (
+    SELECT id,
+           SUM(CASE WHEN transaction_type = 'WITHDRAW' THEN -amount ELSE amount END)
+               OVER (PARTITION BY wallet_id ORDER BY executed_at, id) AS balance
+    FROM transactions_history
+) running
+WHERE th.id = running.id;
+
+ALTER TABLE transactions_history ALTER COLUMN balance_after SET NOT NULL;
+
+-- +migrate Down
+
+ALTER TABLE transactions_history DROP COLUMN balance_after;
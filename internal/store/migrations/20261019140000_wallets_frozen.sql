@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE wallets ADD COLUMN frozen bool not null DEFAULT false;
+
+-- +migrate Down
+
+ALTER TABLE wallets DROP COLUMN frozen;
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+-- Rows written before this migration keep NULL hashes; each wallet's chain starts at its first
+-- transaction written afterwards.
+ALTER TABLE transactions_history ADD COLUMN prev_hash bytea;
+ALTER TABLE transactions_history ADD COLUMN hash bytea;
+
+-- +migrate Down
+
+ALTER TABLE transactions_history DROP COLUMN hash;
+ALTER TABLE transactions_history DROP COLUMN prev_hash;
@@ -0,0 +1,15 @@
+-- +migrate Up
+
+CREATE TABLE idempotency_keys (
+    key varchar primary key,
+    operation_type varchar not null,
+    wallet_id uuid not null,
+    target_wallet_id uuid,
+    amount numeric not null,
+    transaction_ids uuid[] not null DEFAULT '{}',
+    created_at timestamp not null
+);
+
+-- +migrate Down
+
+DROP TABLE idempotency_keys;
@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE transactions_history ADD COLUMN reason varchar not null DEFAULT '';
+
+-- +migrate Down
+
+ALTER TABLE transactions_history DROP COLUMN reason;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+ALTER TABLE wallets ADD COLUMN hot bool not null DEFAULT false;
+
+CREATE TABLE pending_credits (
+    id uuid primary key,
+    wallet_id uuid not null references wallets (id),
+    amount numeric not null check (amount > 0),
+    reason varchar not null DEFAULT '',
+    created_at timestamp not null
+);
+
+CREATE INDEX idx_pending_credits_wallet_id_created_at ON pending_credits (wallet_id, created_at, id);
+
+-- +migrate Down
+
+DROP TABLE pending_credits;
+ALTER TABLE wallets DROP COLUMN hot;
@@ -0,0 +1,7 @@
+-- +migrate Up
+
+ALTER TABLE wallets ADD COLUMN version bigint not null DEFAULT 1;
+
+-- +migrate Down
+
+ALTER TABLE wallets DROP COLUMN version;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+CREATE TABLE rate_limit_buckets (
+    key varchar primary key,
+    tat timestamp not null,
+    allowed bool not null DEFAULT true
+);
+
+CREATE INDEX idx_rate_limit_buckets_tat ON rate_limit_buckets (tat);
+
+-- +migrate Down
+
+DROP TABLE rate_limit_buckets;
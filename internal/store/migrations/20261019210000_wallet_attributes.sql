@@ -0,0 +1,19 @@
+-- +migrate Up
+
+ALTER TABLE wallets ADD COLUMN external_ref varchar(255) not null DEFAULT '';
+ALTER TABLE wallets ADD COLUMN labels varchar(64)[] not null DEFAULT '{}';
+ALTER TABLE wallets ADD COLUMN metadata jsonb not null DEFAULT '{}';
+
+-- There are no tenants yet, so an external reference is unique among all wallets.
+CREATE UNIQUE INDEX idx_wallets_external_ref ON wallets (external_ref) WHERE external_ref <> '';
+CREATE INDEX idx_wallets_labels ON wallets USING gin (labels);
+CREATE INDEX idx_wallets_created_at ON wallets (created_at, id) WHERE deleted = false;
+
+-- +migrate Down
+
+DROP INDEX idx_wallets_created_at;
+DROP INDEX idx_wallets_labels;
+DROP INDEX idx_wallets_external_ref;
+ALTER TABLE wallets DROP COLUMN metadata;
+ALTER TABLE wallets DROP COLUMN labels;
+ALTER TABLE wallets DROP COLUMN external_ref;
@@ -0,0 +1,22 @@
+-- +migrate Up
+
+-- seq numbers the transactions of a wallet in the order they were appended to its hash chain.
+-- Existing transactions were chained in execution order.
+ALTER TABLE transactions_history ADD COLUMN seq bigint;
+
+UPDATE transactions_history th SET seq = numbered.seq
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY wallet_id ORDER BY executed_at, id) AS seq
+    FROM transactions_history
+) numbered
+WHERE th.id = numbered.id;
+
+ALTER TABLE transactions_history ALTER COLUMN seq SET NOT NULL;
+
+-- A second transaction with the same predecessor cannot be appended, so the chain never forks.
+CREATE UNIQUE INDEX idx_transactions_history_wallet_id_seq ON transactions_history (wallet_id, seq);
+
+-- +migrate Down
+
+DROP INDEX idx_transactions_history_wallet_id_seq;
+ALTER TABLE transactions_history DROP COLUMN seq;
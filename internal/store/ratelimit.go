@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+)
+
+// rateLimitPruneInterval is how often buckets that are full again are deleted.
+const rateLimitPruneInterval = time.Minute
+
+// RateLimiter keeps rate limit buckets in Postgres, so that all replicas of the service share
+// them. Replicas should have synchronized clocks, as each takes the current time from its own.
+type RateLimiter struct {
+	db     *pgxpool.Pool
+	pruned atomic.Int64
+}
+
+func (p *Postgres) RateLimiter() *RateLimiter {
+	return &RateLimiter{db: p.db}
+}
+
+// Take takes a request from the bucket of key, creating the bucket when it does not exist. The
+// bucket row is locked by the upsert, so concurrent requests are counted one after the other.
+func (l *RateLimiter) Take(ctx context.Context, key string, limit models.RateLimit) (models.RateDecision, error) {
+	// Timestamp columns keep the wall clock, so the times read back are in UTC too.
+	now := time.Now().UTC()
+
+	if err := l.prune(ctx, now); err != nil {
+		log.Warnf("pruning rate limit buckets: %v", err)
+	}
+
+	query := `	INSERT INTO rate_limit_buckets AS b (key, tat, allowed)
+				VALUES ($1, $2::timestamp + $3 * interval '1 microsecond', true)
+				ON CONFLICT (key) DO UPDATE SET
+					tat = CASE
+						WHEN GREATEST(b.tat, $2) + $3 * interval '1 microsecond' <= $2 + $4 * interval '1 microsecond'
+						THEN GREATEST(b.tat, $2) + $3 * interval '1 microsecond'
+						ELSE b.tat
+					END,
+					allowed = GREATEST(b.tat, $2) + $3 * interval '1 microsecond' <= $2 + $4 * interval '1 microsecond'
+				RETURNING tat, allowed`
+
+	var (
+		tat     time.Time
+		allowed bool
+	)
+
+	interval := limit.Interval()
+
+	err := l.db.QueryRow(ctx, query,
+		key, now, interval.Microseconds(), (time.Duration(limit.Burst)*interval).Microseconds(),
+	).Scan(&tat, &allowed)
+	if err != nil {
+		return models.RateDecision{}, fmt.Errorf("taking rate limit token error: %w", err)
+	}
+
+	return limit.Decision(tat, now, allowed), nil
+}
+
+// prune deletes the buckets that are full again, which behave like buckets that do not exist. At
+// most one call per interval does it.
+func (l *RateLimiter) prune(ctx context.Context, now time.Time) error {
+	last := l.pruned.Load()
+	if now.UnixNano()-last < rateLimitPruneInterval.Nanoseconds() || !l.pruned.CompareAndSwap(last, now.UnixNano()) {
+		return nil
+	}
+
+	if _, err := l.db.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE tat < $1`, now); err != nil {
+		return fmt.Errorf("deleting full rate limit buckets error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+// ReconcileBalances compares the stored balance of every wallet with the sum of its deposits minus
+// its withdrawals and returns the wallets that differ, together with the number of wallets checked.
+func (p *Postgres) ReconcileBalances(ctx context.Context) ([]models.BalanceDrift, int, error) {
+	query := `	SELECT w.id, w.balance, COALESCE(h.computed, 0), w.balance - COALESCE(h.computed, 0)
+				FROM wallets w
+				LEFT JOIN (
+					SELECT wallet_id,
+						SUM(CASE WHEN transaction_type = 'WITHDRAW' THEN -amount ELSE amount END) AS computed
+					FROM transactions_history
+					GROUP BY wallet_id
+				) h ON h.wallet_id = w.id
+				WHERE w.deleted = false`
+
+	rows, err := p.db.Query(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reconciling balances error: %w", err)
+	}
+
+	defer rows.Close()
+
+	var (
+		drifts  = make([]models.BalanceDrift, 0)
+		checked int
+	)
+
+	for rows.Next() {
+		var drift models.BalanceDrift
+
+		if err := rows.Scan(&drift.WalletID, &drift.StoredBalance, &drift.ComputedBalance, &drift.Delta); err != nil {
+			return nil, 0, fmt.Errorf("scanning reconciliation row error: %w", err)
+		}
+
+		checked++
+
+		if drift.Delta != 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows.Err(): %w", err)
+	}
+
+	return drifts, checked, nil
+}
+
+// FreezeWallets blocks balance changes of the given wallets and returns how many were frozen.
+func (p *Postgres) FreezeWallets(ctx context.Context, ids []uuid.UUID) (int, error) {
+	query := `UPDATE wallets SET frozen = true, updated_at = $2, version = version + 1 WHERE id = ANY($1) AND frozen = false`
+
+	tag, err := p.db.Exec(ctx, query, ids, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("freezing wallets error: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// replicaRetryDelay is how long reads skip a replica that failed before trying it again.
+	replicaRetryDelay = 30 * time.Second
+	// replicaLagCheckInterval is how often the replication lag of a replica is checked.
+	replicaLagCheckInterval = time.Second
+)
+
+// replicaLagQuery is the replication lag in seconds: the age of the last replayed transaction,
+// unless everything received is replayed, as the last transaction ages while the primary is idle.
+// It is zero on a server that is not a replica.
+const replicaLagQuery = `	SELECT CASE
+								WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+								ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+							END::float8`
+
+var errReplicaLagging = errors.New("replication lag is above the limit")
+
+type replica struct {
+	pool        *pgxpool.Pool
+	description string
+	// downUntil is the Unix time in nanoseconds until which the replica is skipped.
+	downUntil atomic.Int64
+	// lagCheckedAt is the Unix time in nanoseconds of the last replication lag check.
+	lagCheckedAt atomic.Int64
+}
+
+func (r *replica) up(now time.Time) bool {
+	return now.UnixNano() >= r.downUntil.Load()
+}
+
+func (r *replica) markDown(now time.Time, err error) {
+	r.downUntil.Store(now.Add(replicaRetryDelay).UnixNano())
+
+	log.Warnf("read replica %s is down, reading from the primary for %s: %v", r.description, replicaRetryDelay, err)
+}
+
+// lagCheckDue reports whether the replication lag is to be checked now, at most once per
+// replicaLagCheckInterval.
+func (r *replica) lagCheckDue(now time.Time) bool {
+	checkedAt := r.lagCheckedAt.Load()
+	if now.UnixNano() < checkedAt+replicaLagCheckInterval.Nanoseconds() {
+		return false
+	}
+
+	return r.lagCheckedAt.CompareAndSwap(checkedAt, now.UnixNano())
+}
+
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// checkLag returns an error when the replication lag read with conn exceeds maxLag.
+func checkLag(ctx context.Context, conn rowQuerier, maxLag time.Duration) error {
+	var seconds float64
+
+	if err := conn.QueryRow(ctx, replicaLagQuery).Scan(&seconds); err != nil {
+		return fmt.Errorf("getting replication lag error: %w", err)
+	}
+
+	if lag := time.Duration(seconds * float64(time.Second)); lag > maxLag {
+		return fmt.Errorf("%w: %s is above %s", errReplicaLagging, lag.Round(time.Millisecond), maxLag)
+	}
+
+	return nil
+}
+
+// replicaSet spreads reads over the read replicas in turn.
+type replicaSet struct {
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint32
+}
+
+// candidates returns the replicas that are up, starting with the next one in turn.
+func (s *replicaSet) candidates(now time.Time) []*replica {
+	if len(s.replicas) == 0 {
+		return nil
+	}
+
+	start := int(s.next.Add(1)) % len(s.replicas)
+	candidates := make([]*replica, 0, len(s.replicas))
+
+	for i := range s.replicas {
+		if r := s.replicas[(start+i)%len(s.replicas)]; r.up(now) {
+			candidates = append(candidates, r)
+		}
+	}
+
+	return candidates
+}
+
+func newReplicaSet(ctx context.Context, cfg Config) (*replicaSet, error) {
+	set := &replicaSet{replicas: make([]*replica, 0, len(cfg.ReplicaDSNs)), maxLag: cfg.ReplicaMaxLag}
+
+	for _, dsn := range cfg.ReplicaDSNs {
+		replicaConfig := cfg
+		replicaConfig.DSN = dsn
+
+		poolConfig, err := replicaConfig.poolConfig()
+		if err != nil {
+			set.close()
+
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			set.close()
+
+			return nil, fmt.Errorf("replica pgxpool.NewWithConfig(ctx, poolConfig): %w", err)
+		}
+
+		r := &replica{pool: pool, description: describeConn(poolConfig.ConnConfig)}
+
+		// A replica that is down does not stop the service: reads go to the primary meanwhile.
+		if err := pool.Ping(ctx); err != nil {
+			r.markDown(time.Now(), err)
+		} else {
+			log.Infof("connected to read replica %s", r.description)
+		}
+
+		set.replicas = append(set.replicas, r)
+	}
+
+	return set, nil
+}
+
+func (s *replicaSet) close() {
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+// reader acquires a connection for a read-only query. It comes from a read replica unless ctx
+// asks to read your writes; a replica that cannot hand out a connection or lags behind by more
+// than the configured limit is skipped for a while, and the primary is used when no replica is
+// up. The caller must release the connection.
+func (p *Postgres) reader(ctx context.Context) (*pgxpool.Conn, error) {
+	if !models.ReadYourWrites(ctx) {
+		for _, r := range p.replicas.candidates(time.Now()) {
+			conn, err := r.pool.Acquire(ctx)
+			if err == nil && p.replicas.maxLag > 0 && r.lagCheckDue(time.Now()) {
+				if err = checkLag(ctx, conn, p.replicas.maxLag); err != nil {
+					conn.Release()
+				}
+			}
+
+			if err == nil {
+				return conn, nil
+			}
+
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("replica: %w", err)
+			}
+
+			r.markDown(time.Now(), err)
+		}
+	}
+
+	conn, err := p.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("p.db.Acquire(ctx): %w", err)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaSetCandidates(t *testing.T) {
+	first, second, third := &replica{description: "first"}, &replica{description: "second"}, &replica{description: "third"}
+	set := &replicaSet{replicas: []*replica{first, second, third}}
+	now := time.Now()
+
+	require.Equal(t, []*replica{second, third, first}, set.candidates(now))
+	require.Equal(t, []*replica{third, first, second}, set.candidates(now))
+
+	second.markDown(now, errors.New("connection refused"))
+
+	require.Equal(t, []*replica{first, third}, set.candidates(now))
+	require.Equal(t, []*replica{third, first}, set.candidates(now.Add(replicaRetryDelay-time.Second)))
+	require.Equal(t, []*replica{third, first, second}, set.candidates(now.Add(replicaRetryDelay)))
+
+	require.Empty(t, new(replicaSet).candidates(now))
+}
+
+// lagQuerier answers the replication lag query with a lag in seconds.
+type lagQuerier struct {
+	row fakeRow
+}
+
+func (q lagQuerier) QueryRow(_ context.Context, _ string, _ ...any) pgx.Row {
+	return q.row
+}
+
+func TestCheckLag(t *testing.T) {
+	ctx := context.Background()
+
+	require.NoError(t, checkLag(ctx, lagQuerier{row: fakeRow{values: []any{0.0}}}, 5*time.Second))
+	require.NoError(t, checkLag(ctx, lagQuerier{row: fakeRow{values: []any{4.9}}}, 5*time.Second))
+	require.ErrorIs(t, checkLag(ctx, lagQuerier{row: fakeRow{values: []any{12.5}}}, 5*time.Second), errReplicaLagging)
+
+	errQuery := errors.New("connection reset")
+	require.ErrorIs(t, checkLag(ctx, lagQuerier{row: fakeRow{err: errQuery}}, 5*time.Second), errQuery)
+}
+
+func TestReplicaLagCheckDue(t *testing.T) {
+	r := &replica{description: "replica"}
+	now := time.Now()
+
+	require.True(t, r.lagCheckDue(now))
+	require.False(t, r.lagCheckDue(now))
+	require.False(t, r.lagCheckDue(now.Add(replicaLagCheckInterval-time.Millisecond)))
+	require.True(t, r.lagCheckDue(now.Add(replicaLagCheckInterval)))
+}
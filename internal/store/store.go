@@ -1,105 +1,204 @@
 package store
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
-	"embed"
 	"fmt"
+	"net"
 	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	migrate "github.com/rubenv/sql-migrate"
 	log "github.com/sirupsen/logrus"
 )
 
 type Config struct {
+	// DSN, when set, is the connection string used as is instead of the fields from PGUser to
+	// SSLKey. The pool and session settings below still apply when set.
+	DSN string
+
 	PGUser     string
 	PGPass     string
 	PGHost     string
 	PGPort     string
 	PGDatabase string
+	// SSLMode is the libpq sslmode. Empty means disable.
+	SSLMode string
+	// SSLRootCert is the CA certificate file the server certificate is verified against.
+	SSLRootCert string
+	// SSLCert and SSLKey are the client certificate and key files.
+	SSLCert string
+	SSLKey  string
+
+	// MaxConns, MinConns, MaxConnLifetime and MaxConnIdleTime tune the connection pool. Zero keeps
+	// the value of the DSN or the pgxpool default.
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	ConnectTimeout  time.Duration
+
+	// StatementTimeout aborts statements, migrations included, that run longer. Zero means no limit.
+	StatementTimeout time.Duration
+	// ApplicationName identifies the service in pg_stat_activity.
+	ApplicationName string
+
+	// ReplicaDSNs are the connection strings of read replicas, which serve wallet and history
+	// reads. They share the pool and session settings of the primary.
+	ReplicaDSNs []string
+	// ReplicaMaxLag is the replication lag beyond which a replica is skipped like one that is down.
+	// Zero means the lag is not checked.
+	ReplicaMaxLag time.Duration
+
+	// TxIsolation is the isolation level of the transactions changing balances: read committed,
+	// repeatable read or serializable. Empty means the server default.
+	TxIsolation string
+	// TxMaxRetries is how many times such a transaction is run again after a serialization failure
+	// or a deadlock. TxRetryDelay and TxRetryMaxDelay bound the backoff between attempts.
+	TxMaxRetries    int
+	TxRetryDelay    time.Duration
+	TxRetryMaxDelay time.Duration
 }
 
-//go:embed migrations
-var migrations embed.FS
-
 type Postgres struct {
-	db  *pgxpool.Pool
-	dsn string
+	db       *pgxpool.Pool
+	replicas *replicaSet
+	runner   *txRunner
 }
 
 func New(ctx context.Context, cfg Config) (*Postgres, error) {
-	urlScheme := url.URL{
-		Scheme:   "postgres",
-		User:     url.UserPassword(cfg.PGUser, cfg.PGPass),
-		Host:     fmt.Sprintf("%s:%s", cfg.PGHost, cfg.PGPort),
-		Path:     cfg.PGDatabase,
-		RawQuery: "sslmode=disable",
+	poolConfig, err := cfg.poolConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	dsn := urlScheme.String()
-
-	db, err := pgxpool.New(ctx, dsn)
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return nil, fmt.Errorf("pgxpool.New(ctx, dsn): %w", err)
+		return nil, fmt.Errorf("pgxpool.NewWithConfig(ctx, poolConfig): %w", err)
 	}
 
 	if err := db.Ping(ctx); err != nil {
+		db.Close()
+
 		return nil, fmt.Errorf("db.Ping: %w", err)
 	}
 
-	log.Infof("connected to postgres database %s", dsn)
+	log.Infof("connected to postgres database %s", describeConn(poolConfig.ConnConfig))
+
+	replicas, err := newReplicaSet(ctx, cfg)
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
 
 	return &Postgres{
-		db:  db,
-		dsn: dsn,
+		db:       db,
+		replicas: replicas,
+		runner:   newTxRunner(db, cfg),
 	}, nil
 }
 
-func (p *Postgres) Migrate(direction migrate.MigrationDirection) error {
-	conn, err := sql.Open("pgx", p.dsn)
-	if err != nil {
-		return fmt.Errorf("sql.Open(): %w", err)
+// connString returns the DSN, or builds a URL from the separate connection fields.
+func (cfg Config) connString() string {
+	if cfg.DSN != "" {
+		return cfg.DSN
 	}
 
-	defer func() {
-		err := conn.Close()
-		if err != nil {
-			log.Errorf("conn.Close() err: %v", err)
+	query := url.Values{}
+	query.Set("sslmode", cmp.Or(cfg.SSLMode, "disable"))
+
+	for name, value := range map[string]string{
+		"sslrootcert": cfg.SSLRootCert,
+		"sslcert":     cfg.SSLCert,
+		"sslkey":      cfg.SSLKey,
+	} {
+		if value != "" {
+			query.Set(name, value)
 		}
-	}()
+	}
 
-	assetDir := func() func(string) ([]string, error) {
-		return func(path string) ([]string, error) {
-			dirEntry, err := migrations.ReadDir(path)
-			if err != nil {
-				return nil, fmt.Errorf("migrations.ReadDir(): %w", err)
-			}
+	urlScheme := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.PGUser, cfg.PGPass),
+		Host:     net.JoinHostPort(cfg.PGHost, cfg.PGPort),
+		Path:     cfg.PGDatabase,
+		RawQuery: query.Encode(),
+	}
 
-			entries := make([]string, 0)
+	return urlScheme.String()
+}
 
-			for _, e := range dirEntry {
-				entries = append(entries, e.Name())
-			}
+func (cfg Config) poolConfig() (*pgxpool.Config, error) {
+	// The error does not leak the password: pgx redacts it from the connection string.
+	poolConfig, err := pgxpool.ParseConfig(cfg.connString())
+	if err != nil {
+		return nil, fmt.Errorf("pgxpool.ParseConfig(): %w", err)
+	}
 
-			return entries, nil
-		}
-	}()
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
 
-	asset := migrate.AssetMigrationSource{
-		Asset:    migrations.ReadFile,
-		AssetDir: assetDir,
-		Dir:      "migrations",
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
 	}
 
-	_, err = migrate.Exec(conn, "postgres", asset, direction)
-	if err != nil {
-		return fmt.Errorf("migrate.Exec(...): %w", err)
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if cfg.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = cfg.ApplicationName
+	}
+
+	return poolConfig, nil
+}
+
+// describeConn names the database of a connection for logs, leaving out the password.
+func describeConn(connConfig *pgx.ConnConfig) string {
+	return fmt.Sprintf("%s@%s/%s",
+		connConfig.User,
+		net.JoinHostPort(connConfig.Host, strconv.Itoa(int(connConfig.Port))),
+		connConfig.Database,
+	)
+}
+
+func (p *Postgres) Close() {
+	p.replicas.close()
+	p.db.Close()
+}
+
+// Ping checks that the database is reachable through the connection pool.
+func (p *Postgres) Ping(ctx context.Context) error {
+	if err := p.db.Ping(ctx); err != nil {
+		return fmt.Errorf("p.db.Ping(ctx): %w", err)
 	}
 
 	return nil
 }
 
+func closeSQLConn(conn *sql.DB) {
+	if err := conn.Close(); err != nil {
+		log.Errorf("conn.Close() err: %v", err)
+	}
+}
+
 func (p *Postgres) Truncate(ctx context.Context, tables ...string) error {
 	for _, table := range tables {
 		_, err := p.db.Exec(ctx, "DELETE FROM"+" "+table)
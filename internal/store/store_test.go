@@ -0,0 +1,65 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolConfig(t *testing.T) {
+	cfg := Config{
+		PGUser:           "admin",
+		PGPass:           "p@ss word",
+		PGHost:           "db",
+		PGPort:           "6432",
+		PGDatabase:       "wallets",
+		MaxConns:         20,
+		MinConns:         2,
+		MaxConnLifetime:  time.Hour,
+		MaxConnIdleTime:  time.Minute,
+		ConnectTimeout:   3 * time.Second,
+		StatementTimeout: 1500 * time.Millisecond,
+		ApplicationName:  "wallets-test",
+	}
+
+	poolConfig, err := cfg.poolConfig()
+	require.NoError(t, err)
+	require.Equal(t, "p@ss word", poolConfig.ConnConfig.Password)
+	require.Equal(t, uint16(6432), poolConfig.ConnConfig.Port)
+	require.Nil(t, poolConfig.ConnConfig.TLSConfig)
+	require.Equal(t, int32(20), poolConfig.MaxConns)
+	require.Equal(t, int32(2), poolConfig.MinConns)
+	require.Equal(t, time.Hour, poolConfig.MaxConnLifetime)
+	require.Equal(t, time.Minute, poolConfig.MaxConnIdleTime)
+	require.Equal(t, 3*time.Second, poolConfig.ConnConfig.ConnectTimeout)
+	require.Equal(t, "1500", poolConfig.ConnConfig.RuntimeParams["statement_timeout"])
+	require.Equal(t, "wallets-test", poolConfig.ConnConfig.RuntimeParams["application_name"])
+
+	require.Equal(t, "admin@db:6432/wallets", describeConn(poolConfig.ConnConfig))
+}
+
+func TestPoolConfigTLS(t *testing.T) {
+	poolConfig, err := Config{PGUser: "admin", PGHost: "db", PGPort: "5432", SSLMode: "require"}.poolConfig()
+	require.NoError(t, err)
+	require.NotNil(t, poolConfig.ConnConfig.TLSConfig)
+
+	_, err = Config{PGHost: "db", PGPort: "5432", SSLMode: "verify-full", SSLRootCert: "/missing/ca.pem"}.poolConfig()
+	require.ErrorContains(t, err, "/missing/ca.pem")
+}
+
+func TestPoolConfigDSN(t *testing.T) {
+	poolConfig, err := Config{
+		DSN:      "host=db port=5433 user=admin password=secret dbname=wallets sslmode=disable pool_max_conns=7",
+		PGHost:   "ignored",
+		MinConns: 1,
+	}.poolConfig()
+	require.NoError(t, err)
+	require.Equal(t, "db", poolConfig.ConnConfig.Host)
+	require.Equal(t, int32(7), poolConfig.MaxConns)
+	require.Equal(t, int32(1), poolConfig.MinConns)
+
+	_, err = Config{DSN: "postgres://admin:secret@db:notaport/wallets"}.poolConfig()
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret")
+}
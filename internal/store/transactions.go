@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/jackc/pgx/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+func (p *Postgres) GetTransaction(ctx context.Context, id uuid.UUID) (*models.TransactionResult, error) {
+	var transaction models.TransactionResult
+
+	query := `	SELECT id, wallet_id, amount, transaction_type, executed_at, balance_after, reason
+				FROM transactions_history
+				WHERE id = $1`
+
+	conn, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, query, id).Scan(transactionDest(&transaction)...)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, models.ErrTransactionNotFound
+	case err != nil:
+		return nil, fmt.Errorf("getting transaction by id error: %w", err)
+	}
+
+	return &transaction, nil
+}
+
+// ListTransactions returns transactions matching filter, newest first, starting after params.After.
+func (p *Postgres) ListTransactions(
+	ctx context.Context,
+	filter models.TransactionFilter,
+	params models.HistoryParams,
+) (*models.TransactionsPage, error) {
+	var (
+		afterExecutedAt *time.Time
+		afterID         *uuid.UUID
+	)
+
+	if params.After != nil {
+		afterExecutedAt = &params.After.ExecutedAt
+		afterID = &params.After.TransactionID
+	}
+
+	limit := params.LimitOrDefault()
+
+	query := `	SELECT id, wallet_id, amount, transaction_type, executed_at, balance_after, reason
+				FROM transactions_history
+				WHERE ($1::uuid IS NULL OR wallet_id = $1)
+					AND ($2::varchar IS NULL OR transaction_type = $2)
+					AND ($3::numeric IS NULL OR amount >= $3)
+					AND ($4::numeric IS NULL OR amount <= $4)
+					AND ($5::timestamp IS NULL OR executed_at >= $5)
+					AND ($6::timestamp IS NULL OR executed_at < $6)
+					AND ($7::timestamp IS NULL OR (executed_at, id) < ($7::timestamp, $8::uuid))
+				ORDER BY executed_at DESC, id DESC
+				LIMIT $9`
+
+	conn, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Release()
+
+	rows, err := conn.Query(
+		ctx,
+		query,
+		filter.WalletID,
+		filter.OperationType,
+		filter.MinAmount,
+		filter.MaxAmount,
+		filter.From,
+		filter.To,
+		afterExecutedAt,
+		afterID,
+		limit+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing transactions error: %w", err)
+	}
+
+	defer rows.Close()
+
+	page := &models.TransactionsPage{
+		Transactions: make([]models.TransactionResult, 0, limit),
+	}
+
+	for rows.Next() {
+		var transaction models.TransactionResult
+
+		if err := rows.Scan(transactionDest(&transaction)...); err != nil {
+			return nil, fmt.Errorf("scanning transaction error: %w", err)
+		}
+
+		page.Transactions = append(page.Transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err(): %w", err)
+	}
+
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.NextCursor = &models.TransactionCursor{
+			ExecutedAt:    last.ExecutedAt,
+			TransactionID: last.TransactionID,
+		}
+	}
+
+	return page, nil
+}
+
+// GetBalanceAt returns the balance of a wallet after the last transaction executed at or before at.
+// The running balance stored with every history entry acts as a snapshot, so the lookup does not
+// have to sum the whole history.
+func (p *Postgres) GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (float64, error) {
+	var balance float64
+
+	query := `	SELECT COALESCE((
+					SELECT balance_after
+					FROM transactions_history
+					WHERE wallet_id = $1 AND executed_at <= $2
+					ORDER BY executed_at DESC, id DESC
+					LIMIT 1
+				), 0)`
+
+	conn, err := p.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer conn.Release()
+
+	if err := conn.QueryRow(ctx, query, walletID, at).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("getting balance at time error: %w", err)
+	}
+
+	return balance, nil
+}
+
+// WriteStatement streams the statement of a wallet for period to w. The opening balance and the
+// entries are read from one snapshot, so the statement is consistent even under concurrent writes.
+func (p *Postgres) WriteStatement(
+	ctx context.Context,
+	walletID uuid.UUID,
+	period models.StatementPeriod,
+	w models.StatementWriter,
+) error {
+	conn, err := p.reader(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Release()
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("conn.BeginTx(ctx) err: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Warnf("statement tx.Rollback(ctx) err: %v", err)
+		}
+	}()
+
+	var balance float64
+
+	openingQuery := `	SELECT COALESCE((
+							SELECT balance_after
+							FROM transactions_history
+							WHERE wallet_id = $1 AND executed_at < $2
+							ORDER BY executed_at DESC, id DESC
+							LIMIT 1
+						), 0)`
+
+	if err := tx.QueryRow(ctx, openingQuery, walletID, period.From).Scan(&balance); err != nil {
+		return fmt.Errorf("getting opening balance error: %w", err)
+	}
+
+	if err := w.Opening(period.From, balance); err != nil {
+		return fmt.Errorf("w.Opening() err: %w", err)
+	}
+
+	entriesQuery := `	SELECT id, wallet_id, amount, transaction_type, executed_at, balance_after, reason
+						FROM transactions_history
+						WHERE wallet_id = $1 AND executed_at >= $2 AND executed_at < $3
+						ORDER BY executed_at, id`
+
+	rows, err := tx.Query(ctx, entriesQuery, walletID, period.From, period.To)
+	if err != nil {
+		return fmt.Errorf("listing statement entries error: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.TransactionResult
+
+		if err := rows.Scan(transactionDest(&transaction)...); err != nil {
+			return fmt.Errorf("scanning statement entry error: %w", err)
+		}
+
+		if err := w.Entry(transaction); err != nil {
+			return fmt.Errorf("w.Entry() err: %w", err)
+		}
+
+		balance = transaction.BalanceAfter
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows.Err(): %w", err)
+	}
+
+	if err := w.Closing(period.To, balance); err != nil {
+		return fmt.Errorf("w.Closing() err: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTxRetryDelay    = 10 * time.Millisecond
+	defaultTxRetryMaxDelay = 500 * time.Millisecond
+
+	// The retry budget holds up to retryBudgetTokens retries and earns retryBudgetRatio of a retry
+	// for every committed transaction, so retries stay a small share of the load once it is spent.
+	retryBudgetTokens = 100
+	retryBudgetRatio  = 0.1
+)
+
+//nolint:gochecknoglobals
+var (
+	txRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallets_store_tx_retries_total",
+		Help: "Number of database transactions retried, by operation and SQLSTATE of the failure.",
+	}, []string{"operation", "sqlstate"})
+	txRetriesExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallets_store_tx_retries_exhausted_total",
+		Help: "Number of database transactions given up on, by operation and what ran out.",
+	}, []string{"operation", "limit"})
+)
+
+// txRunner runs the transactions changing balances. Serialization failures and deadlocks abort a
+// transaction only because of the ones running next to it, so it is run again after a jittered
+// backoff, as long as both the attempts of the transaction and the shared retry budget last.
+type txRunner struct {
+	db         txBeginner
+	options    pgx.TxOptions
+	maxRetries int
+	delay      time.Duration
+	maxDelay   time.Duration
+	budget     *retryBudget
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, options pgx.TxOptions) (pgx.Tx, error)
+}
+
+func newTxRunner(db txBeginner, cfg Config) *txRunner {
+	return &txRunner{
+		db:         db,
+		options:    pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(cfg.TxIsolation)},
+		maxRetries: cfg.TxMaxRetries,
+		delay:      cmp.Or(cfg.TxRetryDelay, defaultTxRetryDelay),
+		maxDelay:   cmp.Or(cfg.TxRetryMaxDelay, defaultTxRetryMaxDelay),
+		budget:     &retryBudget{tokens: retryBudgetTokens},
+	}
+}
+
+// run runs fn in a transaction and commits it. fn may run several times, so it must not have
+// effects outside the transaction.
+func (r *txRunner) run(ctx context.Context, operation string, fn func(tx pgx.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := r.attempt(ctx, operation, fn)
+		if err == nil {
+			r.budget.earn()
+
+			return nil
+		}
+
+		sqlState, retryable := retryableState(err)
+
+		switch {
+		case !retryable:
+			return err
+		case attempt >= r.maxRetries:
+			txRetriesExhausted.WithLabelValues(operation, "attempts").Inc()
+
+			return err
+		case !r.budget.spend():
+			txRetriesExhausted.WithLabelValues(operation, "budget").Inc()
+
+			return err
+		}
+
+		txRetries.WithLabelValues(operation, sqlState).Inc()
+
+		backoff := r.backoff(attempt)
+
+		log.Warnf("%s transaction failed with SQLSTATE %s, retrying in %v (retry %d of %d)",
+			operation, sqlState, backoff, attempt+1, r.maxRetries)
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("%w, retry canceled: %w", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *txRunner) attempt(ctx context.Context, operation string, fn func(tx pgx.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, r.options)
+	if err != nil {
+		return fmt.Errorf("p.db.BeginTx(ctx) err: %w", err)
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Warnf("%s tx.Rollback(ctx) err: %v", operation, err)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("transaction commit err: %w", err)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the retry following attempt: a random share of an
+// exponentially growing delay, so that transactions which collided do not collide again.
+func (r *txRunner) backoff(attempt int) time.Duration {
+	ceiling := r.maxDelay
+	if attempt < 32 && r.delay<<attempt < ceiling {
+		ceiling = r.delay << attempt
+	}
+
+	//nolint:gosec // Jitter needs no cryptographic randomness.
+	return ceiling/2 + rand.N(ceiling/2+1)
+}
+
+// retryableState returns the SQLSTATE of err when the transaction failed only because of
+// concurrent ones.
+func retryableState(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+
+	switch pgErr.Code {
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected:
+		return pgErr.Code, true
+	default:
+		return pgErr.Code, false
+	}
+}
+
+// retryBudget limits the retries of all transactions together.
+type retryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+}
+
+func (b *retryBudget) earn() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+retryBudgetRatio, retryBudgetTokens)
+}
+
+func (b *retryBudget) spend() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
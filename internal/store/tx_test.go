@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	commitErr error
+}
+
+func (f *fakeTx) Commit(_ context.Context) error {
+	return f.commitErr
+}
+
+func (f *fakeTx) Rollback(_ context.Context) error {
+	return pgx.ErrTxClosed
+}
+
+type fakeBeginner struct {
+	commitErrs []error
+	begun      int
+	options    pgx.TxOptions
+}
+
+func (f *fakeBeginner) BeginTx(_ context.Context, options pgx.TxOptions) (pgx.Tx, error) {
+	f.options = options
+
+	tx := &fakeTx{}
+	if f.begun < len(f.commitErrs) {
+		tx.commitErr = f.commitErrs[f.begun]
+	}
+
+	f.begun++
+
+	return tx, nil
+}
+
+func newTestRunner(db txBeginner, maxRetries int) *txRunner {
+	return newTxRunner(db, Config{
+		TxIsolation:     "serializable",
+		TxMaxRetries:    maxRetries,
+		TxRetryDelay:    time.Microsecond,
+		TxRetryMaxDelay: time.Millisecond,
+	})
+}
+
+func TestTxRunnerRetries(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+	deadlock := &pgconn.PgError{Code: pgerrcode.DeadlockDetected}
+
+	t.Run("retries serialization failures and deadlocks", func(t *testing.T) {
+		db := &fakeBeginner{commitErrs: []error{serializationFailure, deadlock}}
+
+		err := newTestRunner(db, 3).run(context.Background(), "test", func(_ pgx.Tx) error { return nil })
+		require.NoError(t, err)
+		require.Equal(t, 3, db.begun)
+		require.Equal(t, pgx.Serializable, db.options.IsoLevel)
+	})
+
+	t.Run("gives up after the last retry", func(t *testing.T) {
+		db := &fakeBeginner{}
+		attempts := 0
+
+		err := newTestRunner(db, 2).run(context.Background(), "test", func(_ pgx.Tx) error {
+			attempts++
+
+			return deadlock
+		})
+		require.ErrorIs(t, err, deadlock)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		attempts := 0
+
+		err := newTestRunner(&fakeBeginner{}, 3).run(context.Background(), "test", func(_ pgx.Tx) error {
+			attempts++
+
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("stops when the budget is spent", func(t *testing.T) {
+		runner := newTestRunner(&fakeBeginner{}, 3)
+		runner.budget.tokens = 1
+		attempts := 0
+
+		err := runner.run(context.Background(), "test", func(_ pgx.Tx) error {
+			attempts++
+
+			return serializationFailure
+		})
+		require.ErrorIs(t, err, serializationFailure)
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		runner := newTestRunner(&fakeBeginner{}, 3)
+		runner.delay, runner.maxDelay = time.Hour, time.Hour
+
+		err := runner.run(ctx, "test", func(_ pgx.Tx) error {
+			cancel()
+
+			return serializationFailure
+		})
+		require.ErrorIs(t, err, serializationFailure)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTxRunnerBackoff(t *testing.T) {
+	runner := newTxRunner(nil, Config{TxRetryDelay: 10 * time.Millisecond, TxRetryMaxDelay: 50 * time.Millisecond})
+
+	for attempt, ceiling := range []time.Duration{10, 20, 40, 50, 50} {
+		ceiling *= time.Millisecond
+
+		for range 100 {
+			backoff := runner.backoff(attempt)
+			require.GreaterOrEqual(t, backoff, ceiling/2)
+			require.LessOrEqual(t, backoff, ceiling)
+		}
+	}
+
+	require.LessOrEqual(t, runner.backoff(100), 50*time.Millisecond)
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := &retryBudget{tokens: 1}
+
+	require.True(t, budget.spend())
+	require.False(t, budget.spend())
+
+	// A retry is earned back by ten commits; the eleventh makes up for float rounding.
+	for range 11 {
+		budget.earn()
+	}
+
+	require.True(t, budget.spend())
+
+	budget.tokens = retryBudgetTokens
+	budget.earn()
+	require.InDelta(t, retryBudgetTokens, budget.tokens, 1e-9)
+}
@@ -11,58 +11,63 @@ import (
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	log "github.com/sirupsen/logrus"
 )
 
-func (p *Postgres) CreateWallet(ctx context.Context) (*models.Wallet, error) {
+func (p *Postgres) CreateWallet(ctx context.Context, attrs models.WalletAttributes) (*models.Wallet, error) {
 	createdWallet := new(models.Wallet)
 
 	timeNow := time.Now()
 
-	query := `INSERT INTO wallets (id, balance, created_at, updated_at, deleted) 
-				VALUES ($1, $2, $3, $4, $5)
-				RETURNING id, balance, created_at, updated_at, deleted
+	query := `INSERT INTO wallets (id, balance, created_at, updated_at, deleted, external_ref, labels, metadata) 
+				VALUES ($1, $2, $3, $4, $5, COALESCE($6::varchar, ''), COALESCE($7::varchar[], '{}'), COALESCE($8::jsonb, '{}'))
+				RETURNING id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata
 				`
 
-	if err := p.db.QueryRow(
+	err := p.db.QueryRow(
 		ctx,
 		query,
-		uuid.New(),
-		0,
-		timeNow,
-		timeNow,
-		false,
-	).Scan(
-		&createdWallet.ID,
-		&createdWallet.Balance,
-		&createdWallet.CreatedAt,
-		&createdWallet.UpdatedAt,
-		&createdWallet.Deleted,
-	); err != nil {
+		append([]any{
+			uuid.New(),
+			0,
+			timeNow,
+			timeNow,
+			false,
+		}, attributeArgs(attrs)...)...,
+	).Scan(walletDest(createdWallet)...)
+
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation:
+		return nil, models.ErrExternalRefTaken
+	case err != nil:
 		return nil, fmt.Errorf("creating wallet error: %w", err)
 	}
 
 	return createdWallet, nil
 }
 
+// GetWallet reads a wallet from a read replica, unless ctx asks to read your writes.
 func (p *Postgres) GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
 	var wallet models.Wallet
 
-	query := `	SELECT id, balance, created_at, updated_at, deleted 
+	query := `	SELECT id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata,
+					(SELECT COALESCE(SUM(amount), 0) FROM pending_credits WHERE wallet_id = $1)
 				FROM wallets 
 				WHERE id = $1 AND deleted = false`
 
-	err := p.db.QueryRow(
+	conn, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Release()
+
+	err = conn.QueryRow(
 		ctx,
 		query,
 		id,
-	).Scan(
-		&wallet.ID,
-		&wallet.Balance,
-		&wallet.CreatedAt,
-		&wallet.UpdatedAt,
-		&wallet.Deleted,
-	)
+	).Scan(append(walletDest(&wallet), &wallet.PendingBalance)...)
 
 	switch {
 	case errors.Is(err, pgx.ErrNoRows):
@@ -74,130 +79,451 @@ func (p *Postgres) GetWallet(ctx context.Context, id uuid.UUID) (*models.Wallet,
 	return &wallet, nil
 }
 
-func (p *Postgres) Deposit(ctx context.Context, transaction models.Transaction) error {
-	tx, err := p.db.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("p.db.Begin(ctx) err: %w", err)
+// SetWalletFrozen blocks or unblocks balance changes of a wallet.
+func (p *Postgres) SetWalletFrozen(ctx context.Context, id uuid.UUID, frozen bool) (*models.Wallet, error) {
+	var wallet models.Wallet
+
+	query := `	UPDATE wallets SET frozen = $2, updated_at = $3, version = version + 1
+				WHERE id = $1 AND deleted = false
+				RETURNING id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata`
+
+	err := p.db.QueryRow(ctx, query, id, frozen, time.Now()).Scan(walletDest(&wallet)...)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, models.ErrWalletNotFound
+	case err != nil:
+		return nil, fmt.Errorf("updating wallet frozen error: %w", err)
 	}
 
-	defer func() {
-		err := tx.Rollback(ctx)
-		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
-			log.Warnf("deposit tx.Rollback(ctx) err: %v", err)
+	return &wallet, nil
+}
+
+// CloseWallet deletes a wallet with a zero balance and no pending credits. Its history is kept.
+func (p *Postgres) CloseWallet(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+
+	query := `	UPDATE wallets SET deleted = true, updated_at = $2, version = version + 1
+				WHERE id = $1 AND deleted = false AND balance = 0
+					AND NOT EXISTS (SELECT 1 FROM pending_credits WHERE wallet_id = $1)
+				RETURNING id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata`
+
+	err := p.db.QueryRow(ctx, query, id, time.Now()).Scan(walletDest(&wallet)...)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		if _, err := p.GetWallet(models.WithReadYourWrites(ctx), id); err != nil {
+			return nil, err
 		}
-	}()
 
-	err = p.updateWalletBalance(ctx, tx, transaction.WalletID, transaction.Amount)
-	if err != nil {
-		return models.ErrChangeBalanceData
+		return nil, models.ErrWalletNotEmpty
+	case err != nil:
+		return nil, fmt.Errorf("closing wallet error: %w", err)
 	}
 
-	err = p.saveTransaction(ctx, tx, transaction)
+	return &wallet, nil
+}
+
+// UpdateWallet replaces the attributes of a wallet that are not nil.
+func (p *Postgres) UpdateWallet(ctx context.Context, id uuid.UUID, attrs models.WalletAttributes) (*models.Wallet, error) {
+	var wallet models.Wallet
+
+	query := `	UPDATE wallets SET
+					external_ref = COALESCE($2::varchar, external_ref),
+					labels = COALESCE($3::varchar[], labels),
+					metadata = COALESCE($4::jsonb, metadata),
+					updated_at = $5, version = version + 1
+				WHERE id = $1 AND deleted = false
+				RETURNING id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata`
+
+	err := p.runner.run(ctx, "update wallet", func(tx pgx.Tx) error {
+		if err := checkWalletVersion(ctx, tx, id); err != nil {
+			return err
+		}
+
+		args := append([]any{id}, attributeArgs(attrs)...)
+
+		err := tx.QueryRow(ctx, query, append(args, time.Now())...).Scan(walletDest(&wallet)...)
+
+		var pgErr *pgconn.PgError
+
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return models.ErrWalletNotFound
+		case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation:
+			return models.ErrExternalRefTaken
+		case err != nil:
+			return fmt.Errorf("updating wallet attributes error: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return &wallet, nil
+}
+
+// ListWallets returns wallets matching filter, oldest first, starting after params.After.
+func (p *Postgres) ListWallets(
+	ctx context.Context,
+	filter models.WalletFilter,
+	params models.WalletsParams,
+) (*models.WalletsPage, error) {
+	var (
+		afterCreatedAt *time.Time
+		afterID        *uuid.UUID
+	)
+
+	if params.After != nil {
+		afterCreatedAt = &params.After.CreatedAt
+		afterID = &params.After.WalletID
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("transaction commit err: %w", err)
+	limit := params.LimitOrDefault()
+
+	query := `	SELECT id, balance, created_at, updated_at, deleted, frozen, hot, version, external_ref, labels, metadata
+				FROM wallets
+				WHERE deleted = false
+					AND ($1::varchar IS NULL OR labels @> ARRAY[$1::varchar])
+					AND ($2::varchar IS NULL OR external_ref = $2)
+					AND ($3::timestamp IS NULL OR (created_at, id) > ($3::timestamp, $4::uuid))
+				ORDER BY created_at, id
+				LIMIT $5`
+
+	conn, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	defer conn.Release()
 
-func (p *Postgres) Withdraw(ctx context.Context, transaction models.Transaction) error {
-	tx, err := p.db.Begin(ctx)
+	rows, err := conn.Query(ctx, query, filter.Label, filter.ExternalRef, afterCreatedAt, afterID, limit+1)
 	if err != nil {
-		return fmt.Errorf("p.db.Begin(ctx) err: %w", err)
+		return nil, fmt.Errorf("listing wallets error: %w", err)
 	}
 
-	defer func() {
-		err := tx.Rollback(ctx)
-		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
-			log.Warnf("withdraw tx.Rollback(ctx) err: %v", err)
+	defer rows.Close()
+
+	page := &models.WalletsPage{
+		Wallets: make([]models.Wallet, 0, limit),
+	}
+
+	for rows.Next() {
+		var wallet models.Wallet
+
+		if err := rows.Scan(walletDest(&wallet)...); err != nil {
+			return nil, fmt.Errorf("scanning wallet error: %w", err)
 		}
-	}()
 
-	err = p.updateWalletBalance(ctx, tx, transaction.WalletID, -transaction.Amount)
+		page.Wallets = append(page.Wallets, wallet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err(): %w", err)
+	}
 
-	switch {
-	case errors.Is(err, models.ErrBalanceBelowZero):
-		return models.ErrBalanceBelowZero
-	case err != nil:
-		return models.ErrChangeBalanceData
+	if len(page.Wallets) > limit {
+		page.Wallets = page.Wallets[:limit]
+		last := page.Wallets[limit-1]
+		page.NextCursor = &models.WalletCursor{
+			CreatedAt: last.CreatedAt,
+			WalletID:  last.ID,
+		}
 	}
 
-	err = p.saveTransaction(ctx, tx, transaction)
+	return page, nil
+}
+
+// attributeArgs returns the external reference, labels and metadata of attrs as query arguments.
+// Nil attributes are NULL.
+func attributeArgs(attrs models.WalletAttributes) []any {
+	var metadata *string
+
+	if attrs.Metadata != nil {
+		raw := string(attrs.Metadata)
+		metadata = &raw
+	}
+
+	return []any{attrs.ExternalRef, attrs.Labels, metadata}
+}
+
+func walletDest(wallet *models.Wallet) []any {
+	return []any{
+		&wallet.ID,
+		&wallet.Balance,
+		&wallet.CreatedAt,
+		&wallet.UpdatedAt,
+		&wallet.Deleted,
+		&wallet.Frozen,
+		&wallet.Hot,
+		&wallet.Version,
+		&wallet.ExternalRef,
+		&wallet.Labels,
+		&wallet.Metadata,
+	}
+}
+
+func (p *Postgres) Deposit(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error) {
+	transaction.OperationType = models.OperationDeposit
+
+	var executed *models.TransactionResult
+
+	err := p.runner.run(ctx, "deposit", func(tx pgx.Tx) error {
+		if err := checkWalletVersion(ctx, tx, transaction.WalletID); err != nil {
+			return err
+		}
+
+		hot, err := walletHot(ctx, tx, transaction.WalletID)
+		if err != nil {
+			return err
+		}
+
+		// Switching modes in between loses no deposit: a credit queued for a wallet that is no
+		// longer hot is still applied, and a deposit to a wallet that became hot is applied at once.
+		if hot {
+			executed, err = queuePendingCredit(ctx, tx, transaction)
+
+			return err
+		}
+
+		balance, err := p.updateWalletBalance(ctx, tx, transaction.WalletID, transaction.Amount)
+
+		switch {
+		case errors.Is(err, models.ErrWalletNotFound):
+			return models.ErrWalletNotFound
+		case errors.Is(err, models.ErrWalletFrozen):
+			return models.ErrWalletFrozen
+		case err != nil:
+			return fmt.Errorf("%w: %w", models.ErrChangeBalanceData, err)
+		}
+
+		executed, err = p.saveTransaction(ctx, tx, transaction, balance)
+
+		return err
+	})
 	if err != nil {
+		return nil, err
+	}
+
+	return executed, nil
+}
+
+func (p *Postgres) Withdraw(ctx context.Context, transaction models.Transaction) (*models.TransactionResult, error) {
+	transaction.OperationType = models.OperationWithdraw
+
+	var executed *models.TransactionResult
+
+	err := p.runner.run(ctx, "withdraw", func(tx pgx.Tx) error {
+		if err := checkWalletVersion(ctx, tx, transaction.WalletID); err != nil {
+			return err
+		}
+
+		balance, err := p.updateWalletBalance(ctx, tx, transaction.WalletID, -transaction.Amount)
+
+		switch {
+		case errors.Is(err, models.ErrWalletNotFound):
+			return models.ErrWalletNotFound
+		case errors.Is(err, models.ErrWalletFrozen):
+			return models.ErrWalletFrozen
+		case errors.Is(err, models.ErrBalanceBelowZero):
+			return models.ErrBalanceBelowZero
+		case err != nil:
+			return fmt.Errorf("%w: %w", models.ErrChangeBalanceData, err)
+		}
+
+		executed, err = p.saveTransaction(ctx, tx, transaction, balance)
+
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return executed, nil
+}
+
+// checkWalletVersion fails when ctx expects the wallet at other versions than its current one. The
+// wallet row stays locked until tx ends, so the version cannot change in between.
+func checkWalletVersion(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) error {
+	if !models.HasExpectedVersions(ctx) {
+		return nil
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("transaction commit err: %w", err)
+	var version int64
+
+	query := `	SELECT version
+				FROM wallets
+				WHERE id = $1 AND deleted = false
+				FOR UPDATE`
+
+	err := tx.QueryRow(ctx, query, walletID).Scan(&version)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return models.ErrWalletNotFound
+	case err != nil:
+		return fmt.Errorf("getting wallet version error: %w", err)
 	}
 
-	return nil
+	return models.CheckVersion(ctx, version)
 }
 
-func (p *Postgres) updateWalletBalance(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, amount float64) error {
-	query := `	UPDATE wallets SET balance = balance + $2, updated_at = $3
-                WHERE id = $1 and deleted = false 
-				RETURNING id, balance
-				`
+const (
+	updateBalanceQuery = `	UPDATE wallets SET balance = balance + $2, updated_at = $3, version = version + 1
+							WHERE id = $1 and deleted = false
+							RETURNING balance, frozen`
+
+	lastChainLinkQuery = `	SELECT seq, hash
+							FROM transactions_history
+							WHERE wallet_id = $1
+							ORDER BY seq DESC
+							LIMIT 1`
+
+	insertTransactionQuery = `INSERT INTO transactions_history
+    (id, wallet_id, amount, transaction_type, executed_at, balance_after, reason, seq, prev_hash, hash)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    RETURNING id, wallet_id, amount, transaction_type, executed_at, balance_after, reason`
+)
 
-	_, err := tx.Exec(
-		ctx,
-		query,
-		walletID,
-		amount,
-		time.Now(),
+func (p *Postgres) updateWalletBalance(
+	ctx context.Context,
+	tx pgx.Tx,
+	walletID uuid.UUID,
+	amount float64,
+) (float64, error) {
+	return scanBalanceUpdate(tx.QueryRow(ctx, updateBalanceQuery, walletID, amount, time.Now()))
+}
+
+// scanBalanceUpdate reads the result of updateBalanceQuery.
+func scanBalanceUpdate(row pgx.Row) (float64, error) {
+	var (
+		balance float64
+		frozen  bool
 	)
 
+	err := row.Scan(&balance, &frozen)
+
 	var pgErr *pgconn.PgError
 
 	switch {
 	case errors.Is(err, pgx.ErrNoRows):
-		return models.ErrWalletNotFound
+		return 0, models.ErrWalletNotFound
 	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation:
-		return models.ErrBalanceBelowZero
+		return 0, models.ErrBalanceBelowZero
 	case err != nil:
-		return fmt.Errorf("updating wallet error: %w", err)
+		return 0, fmt.Errorf("updating wallet error: %w", err)
+	case frozen:
+		// The caller rolls the transaction back, so the balance change is discarded.
+		return 0, models.ErrWalletFrozen
 	}
 
-	return nil
+	return balance, nil
 }
 
-func (p *Postgres) saveTransaction(ctx context.Context, tx pgx.Tx, transaction models.Transaction) error {
-	var executedOperation models.Transaction
+func (p *Postgres) saveTransaction(
+	ctx context.Context,
+	tx pgx.Tx,
+	transaction models.Transaction,
+	balanceAfter float64,
+) (*models.TransactionResult, error) {
+	prev, err := scanChainLink(tx.QueryRow(ctx, lastChainLinkQuery, transaction.WalletID))
+	if err != nil {
+		return nil, err
+	}
 
-	query := `INSERT INTO transactions_history
-    (id, wallet_id, amount, transaction_type, executed_at)
-    VALUES ($1, $2, $3, $4, $5)
-    RETURNING id, wallet_id, amount, transaction_type, executed_at`
+	executedOperation := newTransactionResult(transaction, balanceAfter, time.Now())
 
-	err := tx.QueryRow(
+	err = tx.QueryRow(
 		ctx,
-		query,
-		uuid.New(),
-		transaction.WalletID,
-		transaction.Amount,
-		transaction.OperationType,
-		time.Now(),
-	).Scan(
-		&executedOperation.TransactionID,
-		&executedOperation.WalletID,
-		&executedOperation.Amount,
-		&executedOperation.OperationType,
-		&executedOperation.ExecutedAt,
-	)
+		insertTransactionQuery,
+		insertTransactionArgs(executedOperation, prev)...,
+	).Scan(transactionDest(executedOperation)...)
 
 	var pgErr *pgconn.PgError
 
 	switch {
 	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation:
-		return models.ErrWalletNotFound
+		return nil, models.ErrWalletNotFound
 	case err != nil:
-		return fmt.Errorf("transaction writing to database err: %w", err)
+		return nil, fmt.Errorf("transaction writing to database err: %w", err)
+	}
+
+	return executedOperation, nil
+}
+
+func newTransactionResult(
+	transaction models.Transaction,
+	balanceAfter float64,
+	executedAt time.Time,
+) *models.TransactionResult {
+	return &models.TransactionResult{
+		Transaction: models.Transaction{
+			TransactionID: uuid.New(),
+			WalletID:      transaction.WalletID,
+			Amount:        transaction.Amount,
+			OperationType: transaction.OperationType,
+			ExecutedAt:    executedAt,
+			Reason:        transaction.Reason,
+		},
+		BalanceAfter: balanceAfter,
+	}
+}
+
+// insertTransactionArgs returns the arguments of insertTransactionQuery, appending transaction to
+// the wallet's hash chain after prev.
+func insertTransactionArgs(transaction *models.TransactionResult, prev chainLink) []any {
+	return []any{
+		transaction.TransactionID,
+		transaction.WalletID,
+		transaction.Amount,
+		transaction.OperationType,
+		transaction.ExecutedAt,
+		transaction.BalanceAfter,
+		transaction.Reason,
+		prev.seq + 1,
+		prev.hash,
+		transaction.ChainHash(prev.hash),
+	}
+}
+
+func transactionDest(transaction *models.TransactionResult) []any {
+	return []any{
+		&transaction.TransactionID,
+		&transaction.WalletID,
+		&transaction.Amount,
+		&transaction.OperationType,
+		&transaction.ExecutedAt,
+		&transaction.BalanceAfter,
+		&transaction.Reason,
+	}
+}
+
+// chainLink is the position of a transaction in the hash chain of its wallet. Transactions are
+// numbered per wallet from 1 in the order they are appended, which does not depend on the clocks
+// of the hosts that executed them.
+type chainLink struct {
+	seq  int64
+	hash []byte
+}
+
+// next returns the link of transaction appended after l.
+func (l chainLink) next(transaction *models.TransactionResult) chainLink {
+	return chainLink{seq: l.seq + 1, hash: transaction.ChainHash(l.hash)}
+}
+
+// scanChainLink reads the result of lastChainLinkQuery: the link of the latest transaction of the
+// wallet, or the zero link when the wallet has no transactions yet. Its hash is nil when the
+// transaction predates the chain. The caller must hold the wallet row lock, so that no other
+// transaction can be appended to the chain concurrently.
+func scanChainLink(row pgx.Row) (chainLink, error) {
+	var link chainLink
+
+	err := row.Scan(&link.seq, &link.hash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return chainLink{}, fmt.Errorf("getting last chain link error: %w", err)
 	}
 
-	return nil
+	return link, nil
 }
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/config"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/store"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+func (s *IntegrationTestSuite) TestWalletAdministration() {
+	ctx := context.Background()
+
+	wallet, err := s.service.CreateWallet(ctx, models.WalletAttributes{})
+	s.Require().NoError(err)
+
+	s.Run("deposit with reason", func() {
+		executed, err := s.service.Deposit(ctx, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        40,
+			OperationType: models.OperationDeposit,
+			Reason:        "refund of ticket 123",
+		})
+		s.Require().NoError(err)
+		s.Require().Equal("refund of ticket 123", executed.Reason)
+
+		page, err := s.service.ListTransactions(ctx, wallet.ID, models.HistoryParams{})
+		s.Require().NoError(err)
+		s.Require().Len(page.Transactions, 1)
+		s.Require().Equal("refund of ticket 123", page.Transactions[0].Reason)
+
+		verification, err := s.service.VerifyChain(ctx, wallet.ID)
+		s.Require().NoError(err)
+		s.Require().True(verification.Valid)
+	})
+
+	s.Run("freeze and unfreeze", func() {
+		frozen, err := s.service.SetWalletFrozen(ctx, wallet.ID, true)
+		s.Require().NoError(err)
+		s.Require().True(frozen.Frozen)
+
+		_, err = s.service.Withdraw(ctx, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        40,
+			OperationType: models.OperationWithdraw,
+		})
+		s.Require().ErrorIs(err, models.ErrWalletFrozen)
+
+		unfrozen, err := s.service.SetWalletFrozen(ctx, wallet.ID, false)
+		s.Require().NoError(err)
+		s.Require().False(unfrozen.Frozen)
+	})
+
+	s.Run("close", func() {
+		_, err := s.service.CloseWallet(ctx, wallet.ID)
+		s.Require().ErrorIs(err, models.ErrWalletNotEmpty)
+
+		_, err = s.service.Withdraw(ctx, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        40,
+			OperationType: models.OperationWithdraw,
+		})
+		s.Require().NoError(err)
+
+		closed, err := s.service.CloseWallet(ctx, wallet.ID)
+		s.Require().NoError(err)
+		s.Require().True(closed.Deleted)
+
+		_, err = s.service.GetWallet(ctx, wallet.ID)
+		s.Require().ErrorIs(err, models.ErrWalletNotFound)
+
+		page, err := s.service.SearchTransactions(ctx,
+			models.TransactionFilter{WalletID: &wallet.ID}, models.HistoryParams{})
+		s.Require().NoError(err)
+		s.Require().Len(page.Transactions, 2)
+	})
+
+	s.Run("unknown wallet", func() {
+		_, err := s.service.SetWalletFrozen(ctx, uuid.New(), true)
+		s.Require().ErrorIs(err, models.ErrWalletNotFound)
+
+		_, err = s.service.CloseWallet(ctx, uuid.New())
+		s.Require().ErrorIs(err, models.ErrWalletNotFound)
+	})
+
+	s.Run("migration status", func() {
+		states, err := s.store.MigrationStatus()
+		s.Require().NoError(err)
+		s.Require().NotEmpty(states)
+
+		for _, state := range states {
+			s.Require().NotNil(state.AppliedAt, state.ID)
+		}
+
+		plan, err := s.store.PlanMigrations(migrate.Up, 0)
+		s.Require().NoError(err)
+		s.Require().Empty(plan)
+	})
+
+	s.Run("destructive down migration needs confirmation", func() {
+		plan, err := s.store.PlanMigrations(migrate.Down, 1)
+		s.Require().NoError(err)
+		s.Require().Len(plan, 1)
+		s.Require().True(plan[0].Destructive)
+
+		applied, err := s.store.ApplyMigrations(ctx, migrate.Down, store.MigrateOptions{Max: 1})
+		s.Require().ErrorIs(err, store.ErrDestructiveMigration)
+		s.Require().Zero(applied)
+
+		s.Require().NoError(s.store.CheckMigrations(ctx))
+	})
+
+	s.Run("migrations need no pooled connection", func() {
+		cfg, err := config.Load("tests", []string{"-config", "example.env"})
+		s.Require().NoError(err)
+
+		storeCfg := cfg.Store()
+		storeCfg.MaxConns = 1
+
+		db, err := store.New(ctx, storeCfg)
+		s.Require().NoError(err)
+
+		defer db.Close()
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		applied, err := db.ApplyMigrations(timeoutCtx, migrate.Up, store.MigrateOptions{})
+		s.Require().NoError(err)
+		s.Require().Zero(applied)
+	})
+}
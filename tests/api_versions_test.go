@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+const apiV2Address = baseAddress + "/api/v2"
+
+func (s *IntegrationTestSuite) TestAPIVersions() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	walletPath := "/wallets/" + wallet.ID.String()
+
+	s.Run("v2 takes and returns decimal amounts", func() {
+		var httpResp struct {
+			Data map[string]any `json:"data"`
+		}
+
+		resp := s.sendV2Request(ctx, http.MethodPost, walletPath+"/deposits", map[string]any{"amount": "10.25"}, &httpResp)
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+		s.Require().Equal("/api/v2"+walletPath+"/transactions/"+fmt.Sprint(httpResp.Data["id"]), resp.Header.Get("Location"))
+		s.Require().Equal("10.25", httpResp.Data["amount"])
+		s.Require().Equal("10.25", httpResp.Data["balanceAfter"])
+
+		resp = s.sendV2Request(ctx, http.MethodGet, walletPath, nil, &httpResp)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Equal("10.25", httpResp.Data["Balance"])
+	})
+
+	s.Run("v1 sees the same wallet with number amounts", func() {
+		resp := s.sendRequest(ctx, http.MethodGet, "/"+wallet.ID.String(), nil, &rest.HTTPResponse{Data: wallet})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Equal(10.25, wallet.Balance)
+	})
+
+	s.Run("400/v2 number amount", func() {
+		resp := s.sendV2Request(ctx, http.MethodPost, walletPath+"/deposits", map[string]any{"amount": 1}, nil)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+
+	s.Run("404/legacy routes are not in v2", func() {
+		deposit := models.Transaction{WalletID: wallet.ID, Amount: 1, OperationType: models.OperationDeposit}
+
+		resp := s.sendV2Request(ctx, http.MethodPut, "/wallets/deposit", deposit, nil)
+		s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func (s *IntegrationTestSuite) sendV2Request(ctx context.Context, method, endpoint string, body, dest any) *http.Response {
+	s.T().Helper()
+
+	var reqBody []byte
+
+	if body != nil {
+		var err error
+
+		reqBody, err = json.Marshal(body)
+		s.Require().NoError(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiV2Address+endpoint, bytes.NewReader(reqBody))
+	s.Require().NoError(err)
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+
+	defer func() {
+		s.Require().NoError(resp.Body.Close())
+	}()
+
+	if dest != nil {
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(dest))
+	}
+
+	return resp
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestBalanceHistory() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: &wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	beforeDeposit := time.Now().UTC()
+
+	resp = s.sendRequest(ctx, http.MethodPut, "/deposit", models.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        100,
+		OperationType: models.OperationDeposit,
+	}, nil)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	afterDeposit := time.Now().UTC()
+
+	resp = s.sendRequest(ctx, http.MethodPut, "/withdraw", models.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        30,
+		OperationType: models.OperationWithdraw,
+	}, nil)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	s.Run("GET /wallets/{id}/balance", func() {
+		tests := []struct {
+			name    string
+			at      *time.Time
+			balance float64
+		}{
+			{name: "before first transaction", at: &beforeDeposit, balance: 0},
+			{name: "after deposit", at: &afterDeposit, balance: 100},
+			{name: "now", balance: 70},
+		}
+
+		for _, tt := range tests {
+			s.Run(tt.name, func() {
+				balance := new(models.BalanceAt)
+
+				endpoint := "/" + wallet.ID.String() + "/balance"
+				if tt.at != nil {
+					endpoint += "?at=" + url.QueryEscape(tt.at.Format(time.RFC3339Nano))
+				}
+
+				resp := s.sendRequest(ctx, http.MethodGet, endpoint, nil, &rest.HTTPResponse{Data: &balance})
+				s.Require().Equal(http.StatusOK, resp.StatusCode)
+				s.Require().Equal(tt.balance, balance.Balance)
+			})
+		}
+
+		s.Run("400/StatusBadRequest(bad timestamp)", func() {
+			resp := s.sendRequest(ctx, http.MethodGet, "/"+wallet.ID.String()+"/balance?at=yesterday", nil, nil)
+			s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		})
+	})
+
+	s.Run("GET /wallets/{id}/transactions", func() {
+		page := new(rest.TransactionsPage)
+
+		resp := s.sendRequest(ctx, http.MethodGet, "/"+wallet.ID.String()+"/transactions", nil, &rest.HTTPResponse{Data: &page})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Len(page.Transactions, 2)
+		s.Require().Equal(70.0, page.Transactions[0].BalanceAfter)
+		s.Require().Equal(100.0, page.Transactions[1].BalanceAfter)
+	})
+}
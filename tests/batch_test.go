@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestBatch() {
+	ctx := context.Background()
+
+	createWallet := func() *models.Wallet {
+		wallet := new(models.Wallet)
+		resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: &wallet})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+		return wallet
+	}
+
+	balanceOf := func(walletID uuid.UUID) float64 {
+		wallet := new(models.Wallet)
+		resp := s.sendRequest(ctx, http.MethodGet, "/"+walletID.String(), nil, &rest.HTTPResponse{Data: &wallet})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		return wallet.Balance
+	}
+
+	first := createWallet()
+	second := createWallet()
+
+	s.Run("best effort reports every operation", func() {
+		batch := models.Batch{Operations: []models.BatchOperation{
+			{OperationType: models.OperationDeposit, WalletID: first.ID, Amount: 100},
+			{OperationType: models.OperationWithdraw, WalletID: second.ID, Amount: 50},
+			{OperationType: models.OperationTransfer, WalletID: first.ID, TargetWalletID: second.ID, Amount: 30},
+			{OperationType: models.OperationDeposit, WalletID: uuid.New(), Amount: 10},
+		}}
+
+		result := new(rest.BatchResponse)
+		resp := s.sendAPIRequest(ctx, http.MethodPost, "/operations/batch", batch, &rest.HTTPResponse{Data: &result})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Len(result.Results, 4)
+
+		s.Require().Equal("ok", result.Results[0].Status)
+		s.Require().Equal(100.0, result.Results[0].Transactions[0].BalanceAfter)
+
+		s.Require().Equal("failed", result.Results[1].Status)
+		s.Require().Equal(rest.ErrCodeInsufficientFunds, result.Results[1].Error.Code)
+
+		s.Require().Equal("ok", result.Results[2].Status)
+		s.Require().Len(result.Results[2].Transactions, 2)
+		s.Require().Equal(models.OperationWithdraw, result.Results[2].Transactions[0].OperationType)
+		s.Require().Equal(70.0, result.Results[2].Transactions[0].BalanceAfter)
+		s.Require().Equal(models.OperationDeposit, result.Results[2].Transactions[1].OperationType)
+		s.Require().Equal(30.0, result.Results[2].Transactions[1].BalanceAfter)
+
+		s.Require().Equal("failed", result.Results[3].Status)
+		s.Require().Equal(rest.ErrCodeWalletNotFound, result.Results[3].Error.Code)
+
+		s.Require().Equal(70.0, balanceOf(first.ID))
+		s.Require().Equal(30.0, balanceOf(second.ID))
+	})
+
+	s.Run("atomic batch is rolled back on failure", func() {
+		batch := models.Batch{Atomic: true, Operations: []models.BatchOperation{
+			{OperationType: models.OperationDeposit, WalletID: first.ID, Amount: 500},
+			{OperationType: models.OperationWithdraw, WalletID: second.ID, Amount: 1000},
+		}}
+
+		var response rest.HTTPResponse
+		resp := s.sendAPIRequest(ctx, http.MethodPost, "/operations/batch", batch, &response)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Require().Equal(rest.ErrCodeInsufficientFunds, response.Error.Code)
+		s.Require().Equal("operations[1]", response.Error.Details[0].Field)
+
+		s.Require().Equal(70.0, balanceOf(first.ID))
+		s.Require().Equal(30.0, balanceOf(second.ID))
+	})
+
+	s.Run("idempotent retry is replayed", func() {
+		op := models.BatchOperation{
+			IdempotencyKey: "payroll-" + uuid.NewString(),
+			OperationType:  models.OperationDeposit,
+			WalletID:       first.ID,
+			Amount:         5,
+		}
+
+		executed := new(rest.BatchResponse)
+		resp := s.sendAPIRequest(ctx, http.MethodPost, "/operations/batch",
+			models.Batch{Operations: []models.BatchOperation{op}}, &rest.HTTPResponse{Data: &executed})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().False(executed.Results[0].Replayed)
+
+		replayed := new(rest.BatchResponse)
+		resp = s.sendAPIRequest(ctx, http.MethodPost, "/operations/batch",
+			models.Batch{Atomic: true, Operations: []models.BatchOperation{op}}, &rest.HTTPResponse{Data: &replayed})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().True(replayed.Results[0].Replayed)
+		s.Require().Equal(executed.Results[0].Transactions, replayed.Results[0].Transactions)
+
+		s.Require().Equal(75.0, balanceOf(first.ID))
+
+		op.Amount = 6
+
+		resp = s.sendAPIRequest(ctx, http.MethodPost, "/operations/batch",
+			models.Batch{Atomic: true, Operations: []models.BatchOperation{op}}, nil)
+		s.Require().Equal(http.StatusConflict, resp.StatusCode)
+	})
+
+	s.Run("400/StatusBadRequest", func() {
+		batch := models.Batch{Operations: []models.BatchOperation{
+			{OperationType: models.OperationTransfer, WalletID: first.ID, TargetWalletID: first.ID, Amount: 1},
+		}}
+
+		var response rest.HTTPResponse
+		resp := s.sendAPIRequest(ctx, http.MethodPost, "/operations/batch", batch, &response)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Require().Equal("operations[0].targetWalletId", response.Error.Details[0].Field)
+	})
+}
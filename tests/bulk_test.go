@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+func (s *IntegrationTestSuite) TestImportExportWallets() {
+	ctx := context.Background()
+
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	wallets := []models.Wallet{
+		{ID: uuid.New(), Balance: 120.5, CreatedAt: createdAt},
+		{ID: uuid.New(), Balance: 0},
+		{Balance: 7},
+	}
+
+	s.Run("dry run saves nothing", func() {
+		dryRun := []models.Wallet{{ID: uuid.New(), Balance: 1}}
+
+		err := s.service.ImportWallets(ctx, dryRun, true)
+		s.Require().NoError(err)
+
+		_, err = s.service.GetWallet(ctx, dryRun[0].ID)
+		s.Require().ErrorIs(err, models.ErrWalletNotFound)
+	})
+
+	s.Run("import", func() {
+		err := s.service.ImportWallets(ctx, wallets, false)
+		s.Require().NoError(err)
+		s.Require().NotEqual(uuid.Nil, wallets[2].ID)
+
+		wallet, err := s.service.GetWallet(ctx, wallets[0].ID)
+		s.Require().NoError(err)
+		s.Require().Equal(120.5, wallet.Balance)
+		s.Require().Equal(createdAt, wallet.CreatedAt)
+
+		page, err := s.service.ListTransactions(ctx, wallets[0].ID, models.HistoryParams{})
+		s.Require().NoError(err)
+		s.Require().Len(page.Transactions, 1)
+		s.Require().Equal(models.OperationOpeningBalance, page.Transactions[0].OperationType)
+		s.Require().Equal(120.5, page.Transactions[0].BalanceAfter)
+
+		page, err = s.service.ListTransactions(ctx, wallets[1].ID, models.HistoryParams{})
+		s.Require().NoError(err)
+		s.Require().Empty(page.Transactions)
+
+		verification, err := s.service.VerifyChain(ctx, wallets[0].ID)
+		s.Require().NoError(err)
+		s.Require().True(verification.Valid)
+		s.Require().Equal(1, verification.Verified)
+	})
+
+	s.Run("existing wallet fails the whole import", func() {
+		again := []models.Wallet{{ID: uuid.New(), Balance: 3}, {ID: wallets[0].ID, Balance: 3}}
+
+		err := s.service.ImportWallets(ctx, again, false)
+		s.Require().ErrorIs(err, models.ErrDuplicateWalletID)
+
+		_, err = s.service.GetWallet(ctx, again[0].ID)
+		s.Require().ErrorIs(err, models.ErrWalletNotFound)
+	})
+
+	s.Run("negative balance", func() {
+		err := s.service.ImportWallets(ctx, []models.Wallet{{Balance: 1}, {Balance: -1}}, false)
+
+		var itemErr *models.BatchItemError
+		s.Require().ErrorAs(err, &itemErr)
+		s.Require().Equal(1, itemErr.Index)
+		s.Require().ErrorIs(err, models.ErrBalanceBelowZero)
+	})
+
+	s.Run("export", func() {
+		exported := make(map[uuid.UUID]float64)
+
+		err := s.service.ExportWallets(ctx, func(wallet models.Wallet) error {
+			exported[wallet.ID] = wallet.Balance
+
+			return nil
+		})
+		s.Require().NoError(err)
+
+		for _, wallet := range wallets {
+			s.Require().Contains(exported, wallet.ID)
+			s.Require().Equal(wallet.Balance, exported[wallet.ID])
+		}
+	})
+
+	s.Run("export and import keep the attributes", func() {
+		externalRef := "acc-" + uuid.NewString()
+		labels := []string{"merchant"}
+
+		original, err := s.service.CreateWallet(ctx, models.WalletAttributes{
+			ExternalRef: &externalRef,
+			Labels:      &labels,
+			Metadata:    json.RawMessage(`{"name": "Shop"}`),
+		})
+		s.Require().NoError(err)
+
+		_, err = s.service.SetWalletHot(ctx, original.ID, true)
+		s.Require().NoError(err)
+
+		_, err = s.service.SetWalletFrozen(ctx, original.ID, true)
+		s.Require().NoError(err)
+
+		var exported models.Wallet
+
+		err = s.service.ExportWallets(ctx, func(wallet models.Wallet) error {
+			if wallet.ID == original.ID {
+				exported = wallet
+			}
+
+			return nil
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(int64(3), exported.Version)
+
+		// The original keeps its ID and external reference, so the copy gets new ones.
+		imported := exported
+		imported.ID = uuid.New()
+		imported.ExternalRef = "acc-" + uuid.NewString()
+
+		s.Require().NoError(s.service.ImportWallets(ctx, []models.Wallet{imported}, false))
+
+		wallet, err := s.service.GetWallet(models.WithReadYourWrites(ctx), imported.ID)
+		s.Require().NoError(err)
+		s.Require().True(wallet.Frozen)
+		s.Require().True(wallet.Hot)
+		s.Require().Equal(exported.Version, wallet.Version)
+		s.Require().Equal(imported.ExternalRef, wallet.ExternalRef)
+		s.Require().Equal(labels, wallet.Labels)
+		s.Require().JSONEq(`{"name": "Shop"}`, string(wallet.Metadata))
+	})
+
+	s.Run("external reference used twice", func() {
+		err := s.service.ImportWallets(ctx, []models.Wallet{{ExternalRef: "acc-twice"}, {ExternalRef: "acc-twice"}}, false)
+
+		var itemErr *models.BatchItemError
+		s.Require().ErrorAs(err, &itemErr)
+		s.Require().Equal(1, itemErr.Index)
+		s.Require().ErrorIs(err, models.ErrExternalRefTaken)
+	})
+}
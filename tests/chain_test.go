@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestVerifyChain() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: &wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	for _, op := range []struct {
+		endpoint      string
+		operationType string
+		amount        float64
+	}{
+		{endpoint: "/deposit", operationType: models.OperationDeposit, amount: 10.5},
+		{endpoint: "/deposit", operationType: models.OperationDeposit, amount: 0.1},
+		{endpoint: "/withdraw", operationType: models.OperationWithdraw, amount: 3.3},
+	} {
+		resp := s.sendRequest(ctx, http.MethodPut, op.endpoint, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        op.amount,
+			OperationType: op.operationType,
+		}, nil)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	s.Run("200/statusOK", func() {
+		verification := new(models.ChainVerification)
+
+		resp := s.sendAdminRequest(ctx, http.MethodGet, "/admin/wallets/"+wallet.ID.String()+"/chain",
+			testAdminAPIKey, &rest.HTTPResponse{Data: &verification})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().True(verification.Valid)
+		s.Require().Equal(3, verification.Verified)
+		s.Require().Zero(verification.Unchained)
+		s.Require().Nil(verification.BrokenLink)
+	})
+
+	s.Run("404/StatusNotFound", func() {
+		resp := s.sendAdminRequest(ctx, http.MethodGet, "/admin/wallets/"+uuid.NewString()+"/chain", testAdminAPIKey, nil)
+		s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	s.Run("401/StatusUnauthorized", func() {
+		resp := s.sendAdminRequest(ctx, http.MethodGet, "/admin/wallets/"+wallet.ID.String()+"/chain", "", nil)
+		s.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+	})
+}
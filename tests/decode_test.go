@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestRequestDecoding() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	deposit := `{"walletId": "` + wallet.ID.String() + `", "amount": 10, "transactionType": "DEPOSIT"}`
+
+	s.Run("200", func() {
+		resp := s.sendRawRequest(ctx, "/deposit", "application/json", deposit, nil)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	s.Run("400/unknown field", func() {
+		var httpResp rest.HTTPResponse
+
+		body := strings.TrimSuffix(deposit, "}") + `, "currency": "EUR"}`
+
+		resp := s.sendRawRequest(ctx, "/deposit", "application/json", body, &httpResp)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Require().Equal(rest.ErrCodeValidationFailed, httpResp.Error.Code)
+		s.Require().Equal([]rest.FieldError{{Field: "currency", Message: "is not a known field"}}, httpResp.Error.Details)
+	})
+
+	s.Run("400/two values", func() {
+		resp := s.sendRawRequest(ctx, "/deposit", "application/json", deposit+deposit, nil)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+
+	s.Run("413", func() {
+		var httpResp rest.HTTPResponse
+
+		body := strings.TrimSuffix(deposit, "}") + `, "reason": "` + strings.Repeat("x", 2<<20) + `"}`
+
+		resp := s.sendRawRequest(ctx, "/withdraw", "application/json", body, &httpResp)
+		s.Require().Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+		s.Require().Equal(rest.ErrCodeBodyTooLarge, httpResp.Error.Code)
+	})
+
+	s.Run("415", func() {
+		resp := s.sendRawRequest(ctx, "/deposit", "text/plain", deposit, nil)
+		s.Require().Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+
+	s.Run("balance is unchanged by rejected requests", func() {
+		read, err := s.service.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+		s.Require().NoError(err)
+		s.Require().Equal(10.0, read.Balance)
+	})
+}
+
+func (s *IntegrationTestSuite) sendRawRequest(ctx context.Context, endpoint, contentType, body string, dest any) *http.Response {
+	s.T().Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, bindAddress+endpoint, bytes.NewBufferString(body))
+	s.Require().NoError(err)
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+
+	defer func() {
+		s.Require().NoError(resp.Body.Close())
+	}()
+
+	if dest != nil {
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(dest))
+	}
+
+	return resp
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/grpcapi/walletsv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func (s *IntegrationTestSuite) TestGRPC() {
+	conn, err := grpc.NewClient(grpcBindAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+
+	defer func() {
+		s.Require().NoError(conn.Close())
+	}()
+
+	client := walletsv1.NewWalletServiceClient(conn)
+	ctx := context.Background()
+
+	created, err := client.CreateWallet(ctx, &walletsv1.CreateWalletRequest{})
+	s.Require().NoError(err)
+
+	walletID := created.GetWallet().GetId()
+
+	s.Run("Deposit", func() {
+		resp, err := client.Deposit(ctx, &walletsv1.DepositRequest{WalletId: walletID, Amount: 300})
+		s.Require().NoError(err)
+		s.Require().Equal(300.0, resp.GetBalanceAfter())
+		s.Require().False(resp.GetPending())
+		s.Require().Equal("DEPOSIT", resp.GetTransaction().GetTransactionType())
+	})
+
+	s.Run("Deposit to a hot wallet", func() {
+		hot, err := client.CreateWallet(ctx, &walletsv1.CreateWalletRequest{})
+		s.Require().NoError(err)
+
+		_, err = s.service.SetWalletHot(ctx, uuid.MustParse(hot.GetWallet().GetId()), true)
+		s.Require().NoError(err)
+
+		resp, err := client.Deposit(ctx, &walletsv1.DepositRequest{WalletId: hot.GetWallet().GetId(), Amount: 10})
+		s.Require().NoError(err)
+		s.Require().True(resp.GetPending())
+		s.Require().Zero(resp.GetBalanceAfter())
+	})
+
+	s.Run("Withdraw", func() {
+		_, err := client.Withdraw(ctx, &walletsv1.WithdrawRequest{WalletId: walletID, Amount: 100})
+		s.Require().NoError(err)
+
+		_, err = client.Withdraw(ctx, &walletsv1.WithdrawRequest{WalletId: walletID, Amount: 1000})
+		s.Require().Equal(codes.FailedPrecondition, status.Code(err))
+	})
+
+	s.Run("GetWallet", func() {
+		resp, err := client.GetWallet(ctx, &walletsv1.GetWalletRequest{Id: walletID})
+		s.Require().NoError(err)
+		s.Require().Equal(200.0, resp.GetWallet().GetBalance())
+
+		_, err = client.GetWallet(ctx, &walletsv1.GetWalletRequest{Id: uuid.NewString()})
+		s.Require().Equal(codes.NotFound, status.Code(err))
+
+		_, err = client.GetWallet(ctx, &walletsv1.GetWalletRequest{Id: "bad id"})
+		s.Require().Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	s.Run("ListTransactions", func() {
+		first, err := client.ListTransactions(ctx, &walletsv1.ListTransactionsRequest{WalletId: walletID, PageSize: 1})
+		s.Require().NoError(err)
+		s.Require().Len(first.GetTransactions(), 1)
+		s.Require().Equal("WITHDRAW", first.GetTransactions()[0].GetTransactionType())
+		s.Require().NotEmpty(first.GetNextPageToken())
+
+		second, err := client.ListTransactions(ctx, &walletsv1.ListTransactionsRequest{
+			WalletId:  walletID,
+			PageSize:  1,
+			PageToken: first.GetNextPageToken(),
+		})
+		s.Require().NoError(err)
+		s.Require().Len(second.GetTransactions(), 1)
+		s.Require().Equal("DEPOSIT", second.GetTransactions()[0].GetTransactionType())
+		s.Require().Empty(second.GetNextPageToken())
+	})
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type healthReport struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	} `json:"checks"`
+}
+
+func (s *IntegrationTestSuite) TestHealth() {
+	s.Run("GET /healthz", func() {
+		s.Run("200/statusOK", func() {
+			report := s.getHealth("/healthz", http.StatusOK)
+			s.Require().Equal("ok", report.Status)
+		})
+	})
+
+	s.Run("GET /readyz", func() {
+		s.Run("200/statusOK", func() {
+			report := s.getHealth("/readyz", http.StatusOK)
+			s.Require().Equal("ok", report.Status)
+			s.Require().Equal("ok", report.Checks["postgres"].Status)
+			s.Require().Equal("ok", report.Checks["migrations"].Status)
+		})
+	})
+}
+
+func (s *IntegrationTestSuite) getHealth(endpoint string, expectedStatus int) healthReport {
+	s.T().Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, baseAddress+endpoint, nil)
+	s.Require().NoError(err)
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+
+	defer func() {
+		err = resp.Body.Close()
+		s.Require().NoError(err)
+	}()
+
+	s.Require().Equal(expectedStatus, resp.StatusCode)
+
+	var report healthReport
+
+	err = json.NewDecoder(resp.Body).Decode(&report)
+	s.Require().NoError(err)
+
+	return report
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestHotWallet() {
+	ctx := context.Background()
+
+	wallet, err := s.service.CreateWallet(ctx, models.WalletAttributes{})
+	s.Require().NoError(err)
+
+	_, err = s.service.Deposit(ctx, models.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        5,
+		OperationType: models.OperationDeposit,
+	})
+	s.Require().NoError(err)
+
+	wallet, err = s.service.SetWalletHot(ctx, wallet.ID, true)
+	s.Require().NoError(err)
+	s.Require().True(wallet.Hot)
+
+	s.Run("deposits are accepted as pending", func() {
+		result := new(models.TransactionResult)
+
+		resp := s.sendRequest(ctx, http.MethodPut, "/deposit", models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        10,
+			OperationType: models.OperationDeposit,
+		}, &rest.HTTPResponse{Data: result})
+		s.Require().Equal(http.StatusAccepted, resp.StatusCode)
+		s.Require().Empty(resp.Header.Get("Location"))
+		s.Require().True(result.Pending)
+
+		var wg sync.WaitGroup
+
+		for range 20 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := s.service.Deposit(ctx, models.Transaction{
+					WalletID:      wallet.ID,
+					Amount:        0.1,
+					OperationType: models.OperationDeposit,
+				})
+				s.NoError(err)
+			}()
+		}
+
+		wg.Wait()
+
+		read, err := s.service.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+		s.Require().NoError(err)
+		s.Require().Equal(5.0, read.Balance)
+		s.Require().InDelta(12.0, read.PendingBalance, 1e-9)
+	})
+
+	s.Run("pending credits are not spendable", func() {
+		_, err := s.service.Withdraw(ctx, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        6,
+			OperationType: models.OperationWithdraw,
+		})
+		s.Require().ErrorIs(err, models.ErrBalanceBelowZero)
+	})
+
+	s.Run("wallet with pending credits cannot be closed", func() {
+		_, err := s.service.CloseWallet(ctx, wallet.ID)
+		s.Require().ErrorIs(err, models.ErrWalletNotEmpty)
+	})
+
+	s.Run("credits of a frozen wallet stay pending", func() {
+		_, err := s.service.SetWalletFrozen(ctx, wallet.ID, true)
+		s.Require().NoError(err)
+
+		_, err = s.store.ApplyPendingCredits(ctx, 1000)
+		s.Require().NoError(err)
+
+		read, err := s.service.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+		s.Require().NoError(err)
+		s.Require().Equal(5.0, read.Balance)
+
+		_, err = s.service.SetWalletFrozen(ctx, wallet.ID, false)
+		s.Require().NoError(err)
+	})
+
+	s.Run("pending credits are applied in batches", func() {
+		for {
+			applied, err := s.store.ApplyPendingCredits(ctx, 5)
+			s.Require().NoError(err)
+
+			if applied == 0 {
+				break
+			}
+		}
+
+		read, err := s.service.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+		s.Require().NoError(err)
+		s.Require().InDelta(17.0, read.Balance, 1e-9)
+		s.Require().Zero(read.PendingBalance)
+
+		page, err := s.service.ListTransactions(models.WithReadYourWrites(ctx), wallet.ID, models.HistoryParams{})
+		s.Require().NoError(err)
+		s.Require().Len(page.Transactions, 22)
+
+		verification, err := s.service.VerifyChain(ctx, wallet.ID)
+		s.Require().NoError(err)
+		s.Require().True(verification.Valid)
+		s.Require().Equal(22, verification.Verified)
+
+		drifts, _, err := s.store.ReconcileBalances(ctx)
+		s.Require().NoError(err)
+
+		for _, drift := range drifts {
+			s.Require().NotEqual(wallet.ID, drift.WalletID)
+		}
+	})
+
+	s.Run("applied deposit keeps its ID", func() {
+		result, err := s.service.Deposit(ctx, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        1,
+			OperationType: models.OperationDeposit,
+		})
+		s.Require().NoError(err)
+		s.Require().True(result.Pending)
+
+		_, err = s.service.GetTransaction(ctx, result.TransactionID)
+		s.Require().ErrorIs(err, models.ErrTransactionNotFound)
+
+		_, err = s.store.ApplyPendingCredits(ctx, 1000)
+		s.Require().NoError(err)
+
+		applied, err := s.service.GetTransaction(models.WithReadYourWrites(ctx), result.TransactionID)
+		s.Require().NoError(err)
+		s.Require().InDelta(18.0, applied.BalanceAfter, 1e-9)
+	})
+
+	s.Run("batch deposits are queued as pending", func() {
+		results, err := s.service.ExecuteBatch(ctx, models.Batch{Atomic: true, Operations: []models.BatchOperation{
+			{OperationType: models.OperationDeposit, WalletID: wallet.ID, Amount: 1},
+		}})
+		s.Require().NoError(err)
+		s.Require().Len(results, 1)
+		s.Require().True(results[0].Transactions[0].Pending)
+
+		read, err := s.service.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+		s.Require().NoError(err)
+		s.Require().InDelta(18.0, read.Balance, 1e-9)
+		s.Require().InDelta(1.0, read.PendingBalance, 1e-9)
+
+		_, err = s.store.ApplyPendingCredits(ctx, 1000)
+		s.Require().NoError(err)
+
+		applied, err := s.service.GetTransaction(models.WithReadYourWrites(ctx), results[0].Transactions[0].TransactionID)
+		s.Require().NoError(err)
+		s.Require().InDelta(19.0, applied.BalanceAfter, 1e-9)
+	})
+
+	s.Run("cold wallet deposits are applied immediately", func() {
+		_, err := s.service.SetWalletHot(ctx, wallet.ID, false)
+		s.Require().NoError(err)
+
+		result, err := s.service.Deposit(ctx, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        2,
+			OperationType: models.OperationDeposit,
+		})
+		s.Require().NoError(err)
+		s.Require().False(result.Pending)
+		s.Require().InDelta(21.0, result.BalanceAfter, 1e-9)
+	})
+
+	s.Run("deposits to unknown wallets are not queued", func() {
+		_, err := s.service.Deposit(ctx, models.Transaction{
+			WalletID:      uuid.New(),
+			Amount:        1,
+			OperationType: models.OperationDeposit,
+		})
+		s.Require().ErrorIs(err, models.ErrWalletNotFound)
+	})
+}
@@ -5,10 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"os"
 	"testing"
 
 	"github.com/iurikman/wallets/internal/config"
+	"github.com/iurikman/wallets/internal/grpcapi"
 	"github.com/iurikman/wallets/internal/rest"
 	"github.com/iurikman/wallets/internal/service"
 	"github.com/iurikman/wallets/internal/store"
@@ -17,7 +17,12 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
-const bindAddress = "http://localhost:8080/api/v1/wallets"
+const (
+	baseAddress     = "http://localhost:8080"
+	apiAddress      = baseAddress + "/api/v1"
+	bindAddress     = apiAddress + "/wallets"
+	grpcBindAddress = "localhost:9090"
+)
 
 type IntegrationTestSuite struct {
 	suite.Suite
@@ -25,6 +30,8 @@ type IntegrationTestSuite struct {
 	store   *store.Postgres
 	service *service.Service
 	server  *rest.Server
+	recon   *service.Reconciler
+	grpc    *grpcapi.Server
 }
 
 func TestIntegrationTestSuite(t *testing.T) {
@@ -35,15 +42,10 @@ func (s *IntegrationTestSuite) SetupSuite() {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancel = cancel
 
-	cfg := config.NewConfig()
+	cfg, err := config.Load("tests", []string{"-config", "example.env"})
+	s.Require().NoError(err)
 
-	db, err := store.New(ctx, store.Config{
-		PGUser:     cfg.PostgresUser,
-		PGPass:     cfg.PostgresPassword,
-		PGHost:     cfg.PostgresHost,
-		PGPort:     cfg.PostgresPort,
-		PGDatabase: cfg.PostgresDatabase,
-	})
+	db, err := store.New(ctx, cfg.Store())
 	s.Require().NoError(err)
 
 	s.store = db
@@ -51,18 +53,43 @@ func (s *IntegrationTestSuite) SetupSuite() {
 	err = s.store.Migrate(migrate.Up)
 	s.Require().NoError(err)
 
-	err = s.store.Truncate(ctx, "transactions_history", "wallets")
+	err = s.store.Truncate(ctx, "idempotency_keys", "rate_limit_buckets", "pending_credits", "transactions_history", "wallets")
 	s.Require().NoError(err)
 
 	s.service = service.New(db)
 
-	s.server, err = rest.NewServer(rest.ServerConfig{BindAddress: os.Getenv("BIND_ADDRESS")}, s.service)
+	s.recon = service.NewReconciler(db, service.ReconcilerConfig{
+		Interval:      cfg.ReconcileInterval,
+		FreezeDrifted: cfg.ReconcileFreezeDrifted,
+	})
+
+	s.server, err = rest.NewServer(
+		rest.ServerConfig{BindAddress: cfg.BindAddress, AdminAPIKeys: cfg.AdminAPIKeys},
+		s.service,
+		s.recon,
+	)
 	s.Require().NoError(err)
 
+	s.server.AddReadinessCheck("postgres", db.Ping)
+	s.server.AddReadinessCheck("migrations", db.CheckMigrations)
+	s.server.AddReadinessCheck("reconciler", s.recon.Check)
+
 	go func() {
 		err := s.server.Start(ctx)
 		s.Require().NoError(err)
 	}()
+
+	go func() {
+		err := s.recon.Start(ctx)
+		s.Require().NoError(err)
+	}()
+
+	s.grpc = grpcapi.NewServer(grpcapi.ServerConfig{BindAddress: cfg.GRPCBindAddress}, s.service)
+
+	go func() {
+		err := s.grpc.Start(ctx)
+		s.Require().NoError(err)
+	}()
 }
 
 func (s *IntegrationTestSuite) TearDownSuite() {
@@ -72,10 +99,22 @@ func (s *IntegrationTestSuite) TearDownSuite() {
 func (s *IntegrationTestSuite) sendRequest(ctx context.Context, method, endpoint string, body interface{}, dest interface{}) *http.Response {
 	s.T().Helper()
 
-	reqBody, err := json.Marshal(body)
-	s.Require().NoError(err)
+	return s.sendAPIRequest(ctx, method, "/wallets"+endpoint, body, dest)
+}
+
+func (s *IntegrationTestSuite) sendAPIRequest(ctx context.Context, method, endpoint string, body interface{}, dest interface{}) *http.Response {
+	s.T().Helper()
+
+	var reqBody []byte
+
+	if body != nil {
+		var err error
+
+		reqBody, err = json.Marshal(body)
+		s.Require().NoError(err)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, method, bindAddress+endpoint, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, method, apiAddress+endpoint, bytes.NewBuffer(reqBody))
 	s.Require().NoError(err)
 
 	req.Header.Set("Content-Type", "application/json")
@@ -95,3 +134,29 @@ func (s *IntegrationTestSuite) sendRequest(ctx context.Context, method, endpoint
 
 	return resp
 }
+
+func (s *IntegrationTestSuite) sendAdminRequest(ctx context.Context, method, endpoint, apiKey string, dest interface{}) *http.Response {
+	s.T().Helper()
+
+	req, err := http.NewRequestWithContext(ctx, method, apiAddress+endpoint, nil)
+	s.Require().NoError(err)
+
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+
+	defer func() {
+		err = resp.Body.Close()
+		s.Require().NoError(err)
+	}()
+
+	if dest != nil {
+		err = json.NewDecoder(resp.Body).Decode(&dest)
+		s.Require().NoError(err)
+	}
+
+	return resp
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestWalletOperations() {
+	ctx := context.Background()
+
+	source := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: source})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	target := new(models.Wallet)
+	resp = s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: target})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	sourcePath := "/" + source.ID.String()
+
+	s.Run("201/deposit", func() {
+		result := new(models.TransactionResult)
+
+		resp := s.sendRequest(ctx, http.MethodPost, sourcePath+"/deposits", rest.OperationRequest{Amount: 100},
+			&rest.HTTPResponse{Data: result})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+		s.Require().Equal("/api/v1/wallets"+sourcePath+"/transactions/"+result.TransactionID.String(), resp.Header.Get("Location"))
+		s.Require().Equal(source.ID, result.WalletID)
+		s.Require().Equal(models.OperationDeposit, result.OperationType)
+		s.Require().Equal(100.0, result.BalanceAfter)
+
+		created := new(models.TransactionResult)
+
+		resp = s.sendAPIRequest(ctx, http.MethodGet, strings.TrimPrefix(resp.Header.Get("Location"), "/api/v1"), nil, &rest.HTTPResponse{Data: created})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Equal(result.TransactionID, created.TransactionID)
+		s.Require().Equal(100.0, created.BalanceAfter)
+	})
+
+	s.Run("201/withdrawal", func() {
+		result := new(models.TransactionResult)
+
+		resp := s.sendRequest(ctx, http.MethodPost, sourcePath+"/withdrawals", rest.OperationRequest{Amount: 30, Reason: "payout"},
+			&rest.HTTPResponse{Data: result})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+		s.Require().Equal(models.OperationWithdraw, result.OperationType)
+		s.Require().Equal("payout", result.Reason)
+		s.Require().Equal(70.0, result.BalanceAfter)
+	})
+
+	s.Run("201/transfer", func() {
+		var results []models.TransactionResult
+
+		resp := s.sendRequest(ctx, http.MethodPost, sourcePath+"/transfers",
+			rest.TransferRequest{TargetWalletID: target.ID, Amount: 20}, &rest.HTTPResponse{Data: &results})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+		s.Require().Len(results, 2)
+		s.Require().Equal(source.ID, results[0].WalletID)
+		s.Require().Equal(50.0, results[0].BalanceAfter)
+		s.Require().Equal(target.ID, results[1].WalletID)
+		s.Require().Equal(20.0, results[1].BalanceAfter)
+	})
+
+	s.Run("400/insufficient funds", func() {
+		resp := s.sendRequest(ctx, http.MethodPost, sourcePath+"/transfers",
+			rest.TransferRequest{TargetWalletID: target.ID, Amount: 1000}, nil)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+
+	s.Run("400/transfer to the same wallet", func() {
+		var httpResp rest.HTTPResponse
+
+		resp := s.sendRequest(ctx, http.MethodPost, sourcePath+"/transfers",
+			rest.TransferRequest{TargetWalletID: source.ID, Amount: 1}, &httpResp)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Require().Equal([]rest.FieldError{{Field: "targetWalletId", Message: models.ErrTransferToSameWallet.Error()}}, httpResp.Error.Details)
+	})
+
+	s.Run("404/unknown wallet", func() {
+		resp := s.sendRequest(ctx, http.MethodPost, "/"+uuid.UUID{1}.String()+"/deposits",
+			rest.OperationRequest{Amount: 1}, nil)
+		s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	s.Run("legacy routes are deprecated", func() {
+		deposit := models.Transaction{WalletID: source.ID, Amount: 1, OperationType: models.OperationDeposit}
+
+		resp := s.sendRequest(ctx, http.MethodPut, "/deposit", deposit, nil)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Equal("true", resp.Header.Get("Deprecation"))
+		s.Require().Equal(`</api/v1/wallets/`+source.ID.String()+`/deposits>; rel="successor-version"`, resp.Header.Get("Link"))
+	})
+}
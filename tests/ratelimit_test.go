@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+)
+
+func (s *IntegrationTestSuite) TestPostgresRateLimiter() {
+	ctx := context.Background()
+	limiter := s.store.RateLimiter()
+	limit := models.RateLimit{Rate: 0.01, Burst: 3}
+
+	s.Run("bucket runs empty", func() {
+		key := "test:" + uuid.NewString()
+
+		for remaining := 2; remaining >= 0; remaining-- {
+			decision, err := limiter.Take(ctx, key, limit)
+			s.Require().NoError(err)
+			s.Require().True(decision.Allowed)
+			s.Require().Equal(remaining, decision.Remaining)
+		}
+
+		decision, err := limiter.Take(ctx, key, limit)
+		s.Require().NoError(err)
+		s.Require().False(decision.Allowed)
+		s.Require().InDelta(100*time.Second, decision.RetryAfter, float64(time.Second))
+	})
+
+	s.Run("bucket refills", func() {
+		key := "test:" + uuid.NewString()
+		fast := models.RateLimit{Rate: 20, Burst: 1}
+
+		decision, err := limiter.Take(ctx, key, fast)
+		s.Require().NoError(err)
+		s.Require().True(decision.Allowed)
+
+		time.Sleep(fast.Interval())
+
+		decision, err = limiter.Take(ctx, key, fast)
+		s.Require().NoError(err)
+		s.Require().True(decision.Allowed)
+	})
+
+	s.Run("concurrent requests share the bucket", func() {
+		key := "test:" + uuid.NewString()
+
+		var (
+			wg      sync.WaitGroup
+			allowed atomic.Int32
+		)
+
+		for range 20 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				decision, err := limiter.Take(ctx, key, limit)
+				s.NoError(err)
+
+				if decision.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		s.Require().Equal(int32(3), allowed.Load())
+	})
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestReconciliation() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: &wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	resp = s.sendRequest(ctx, http.MethodPut, "/deposit", models.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        300,
+		OperationType: "DEPOSIT",
+	}, nil)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	s.Run("POST /admin/reconciliation", func() {
+		s.Run("200/statusOK", func() {
+			report := new(models.ReconciliationReport)
+
+			resp := s.sendAdminRequest(ctx, http.MethodPost, "/admin/reconciliation", testAdminAPIKey,
+				&rest.HTTPResponse{Data: &report})
+			s.Require().Equal(http.StatusOK, resp.StatusCode)
+			s.Require().Empty(report.Error)
+			s.Require().GreaterOrEqual(report.WalletsChecked, 1)
+			s.Require().Empty(report.Drifts)
+			s.Require().False(report.FinishedAt.Before(report.StartedAt))
+		})
+
+		s.Run("401/StatusUnauthorized", func() {
+			resp := s.sendAdminRequest(ctx, http.MethodPost, "/admin/reconciliation", "", nil)
+			s.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+		})
+	})
+
+	s.Run("GET /admin/reconciliation", func() {
+		s.Run("200/statusOK", func() {
+			report := new(models.ReconciliationReport)
+
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/admin/reconciliation", testAdminAPIKey,
+				&rest.HTTPResponse{Data: &report})
+			s.Require().Equal(http.StatusOK, resp.StatusCode)
+			s.Require().Empty(report.Drifts)
+		})
+	})
+
+	s.Run("GET /metrics", func() {
+		resp := s.getRaw(ctx, baseAddress+"/metrics")
+
+		defer resp.Body.Close()
+
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		s.Require().NoError(err)
+		s.Require().Contains(string(body), "wallets_reconciliation_last_run_timestamp_seconds")
+	})
+}
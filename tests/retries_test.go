@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"sync"
+
+	"github.com/iurikman/wallets/internal/config"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/store"
+)
+
+func (s *IntegrationTestSuite) TestSerializableRetries() {
+	ctx := context.Background()
+
+	cfg, err := config.Load("tests", []string{
+		"-config", "example.env", "-postgres-tx-isolation", "serializable", "-postgres-tx-max-retries", "50",
+	})
+	s.Require().NoError(err)
+
+	db, err := store.New(ctx, cfg.Store())
+	s.Require().NoError(err)
+
+	defer db.Close()
+
+	wallet, err := s.service.CreateWallet(ctx, models.WalletAttributes{})
+	s.Require().NoError(err)
+
+	var wg sync.WaitGroup
+
+	// Concurrent updates of the wallet row fail to serialize, and succeed when retried.
+	for range 20 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := db.Deposit(ctx, models.Transaction{WalletID: wallet.ID, Amount: 1})
+			s.NoError(err)
+		}()
+	}
+
+	wg.Wait()
+
+	read, err := db.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(20.0, read.Balance)
+
+	verification, err := s.service.VerifyChain(ctx, wallet.ID)
+	s.Require().NoError(err)
+	s.Require().True(verification.Valid)
+}
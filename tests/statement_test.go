@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestStatement() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: &wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	for _, op := range []struct {
+		endpoint string
+		amount   float64
+	}{
+		{endpoint: "/deposit", amount: 40},
+		{endpoint: "/deposit", amount: 60},
+		{endpoint: "/withdraw", amount: 25},
+	} {
+		resp := s.sendRequest(ctx, http.MethodPut, op.endpoint, models.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        op.amount,
+			OperationType: models.OperationDeposit,
+		}, nil)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+	}
+
+	statementURL := bindAddress + "/" + wallet.ID.String() + "/statement"
+
+	s.Run("csv", func() {
+		resp := s.getRaw(ctx, statementURL+"?format=csv")
+
+		defer resp.Body.Close()
+
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Contains(resp.Header.Get("Content-Type"), "text/csv")
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		s.Require().NoError(err)
+		s.Require().Len(records, 6)
+		s.Require().Equal([]string{"opening", "0"}, []string{records[1][0], records[1][5]})
+		s.Require().Equal([]string{"transaction", "WITHDRAW", "75"}, []string{records[4][0], records[4][3], records[4][5]})
+		s.Require().Equal([]string{"closing", "75"}, []string{records[5][0], records[5][5]})
+	})
+
+	s.Run("jsonl", func() {
+		resp := s.getRaw(ctx, statementURL+"?format=jsonl")
+
+		defer resp.Body.Close()
+
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		records := make([]map[string]any, 0)
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			record := make(map[string]any)
+			s.Require().NoError(json.Unmarshal(scanner.Bytes(), &record))
+			records = append(records, record)
+		}
+
+		s.Require().NoError(scanner.Err())
+		s.Require().Len(records, 5)
+		s.Require().Equal("opening", records[0]["record"])
+		s.Require().InDelta(40.0, records[1]["balanceAfter"], 0)
+		s.Require().Equal("closing", records[4]["record"])
+		s.Require().InDelta(75.0, records[4]["balance"], 0)
+	})
+
+	s.Run("400/StatusBadRequest(format)", func() {
+		resp := s.getRaw(ctx, statementURL+"?format=xml")
+
+		defer resp.Body.Close()
+
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func (s *IntegrationTestSuite) getRaw(ctx context.Context, url string) *http.Response {
+	s.T().Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	s.Require().NoError(err)
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+
+	return resp
+}
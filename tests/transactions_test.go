@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+const testAdminAPIKey = "local-admin-key"
+
+func (s *IntegrationTestSuite) TestTransactions() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: &wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	deposit := new(models.TransactionResult)
+	resp = s.sendRequest(ctx, http.MethodPut, "/deposit", models.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        700,
+		OperationType: "DEPOSIT",
+	}, &rest.HTTPResponse{Data: &deposit})
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	resp = s.sendRequest(ctx, http.MethodPut, "/withdraw", models.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        200,
+		OperationType: "WITHDRAW",
+	}, nil)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	s.Run("GET /transactions/{id}", func() {
+		s.Run("200/statusOK", func() {
+			transaction := new(models.Transaction)
+
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/transactions/"+deposit.TransactionID.String(),
+				testAdminAPIKey, &rest.HTTPResponse{Data: &transaction})
+			s.Require().Equal(http.StatusOK, resp.StatusCode)
+			s.Require().Equal(deposit.TransactionID, transaction.TransactionID)
+			s.Require().Equal(700.0, transaction.Amount)
+		})
+
+		s.Run("404/StatusNotFound", func() {
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/transactions/"+uuid.NewString(), testAdminAPIKey, nil)
+			s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+		})
+
+		s.Run("401/StatusUnauthorized", func() {
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/transactions/"+deposit.TransactionID.String(), "", nil)
+			s.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+
+			resp = s.sendAdminRequest(ctx, http.MethodGet, "/transactions/"+deposit.TransactionID.String(), "wrong", nil)
+			s.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+		})
+	})
+
+	s.Run("GET /transactions", func() {
+		s.Run("200/statusOK(filters)", func() {
+			page := new(rest.TransactionsPage)
+
+			query := url.Values{}
+			query.Set("walletId", wallet.ID.String())
+			query.Set("transactionType", "WITHDRAW")
+			query.Set("minAmount", "100")
+
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/transactions?"+query.Encode(),
+				testAdminAPIKey, &rest.HTTPResponse{Data: &page})
+			s.Require().Equal(http.StatusOK, resp.StatusCode)
+			s.Require().Len(page.Transactions, 1)
+			s.Require().Equal(200.0, page.Transactions[0].Amount)
+		})
+
+		s.Run("200/statusOK(pagination)", func() {
+			first := new(rest.TransactionsPage)
+
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/transactions?limit=1&walletId="+wallet.ID.String(),
+				testAdminAPIKey, &rest.HTTPResponse{Data: &first})
+			s.Require().Equal(http.StatusOK, resp.StatusCode)
+			s.Require().Len(first.Transactions, 1)
+			s.Require().NotEmpty(first.NextCursor)
+
+			second := new(rest.TransactionsPage)
+
+			resp = s.sendAdminRequest(ctx, http.MethodGet,
+				"/transactions?limit=1&walletId="+wallet.ID.String()+"&cursor="+first.NextCursor,
+				testAdminAPIKey, &rest.HTTPResponse{Data: &second})
+			s.Require().Equal(http.StatusOK, resp.StatusCode)
+			s.Require().Len(second.Transactions, 1)
+			s.Require().Equal(deposit.TransactionID, second.Transactions[0].TransactionID)
+			s.Require().Empty(second.NextCursor)
+		})
+
+		s.Run("400/StatusBadRequest(amount range)", func() {
+			resp := s.sendAdminRequest(ctx, http.MethodGet, "/transactions?minAmount=10&maxAmount=1", testAdminAPIKey, nil)
+			s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestWalletVersions() {
+	ctx := context.Background()
+
+	wallet := new(models.Wallet)
+	resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: wallet})
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+	s.Require().Equal(`"1"`, resp.Header.Get("ETag"))
+
+	deposit := models.Transaction{WalletID: wallet.ID, Amount: 10, OperationType: models.OperationDeposit}
+	withdrawal := models.Transaction{WalletID: wallet.ID, Amount: 4, OperationType: models.OperationWithdraw}
+
+	s.Run("deposit with the current version", func() {
+		resp := s.sendConditionalRequest(ctx, http.MethodPut, "/deposit", `"1"`, deposit)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		resp = s.sendRequest(ctx, http.MethodGet, "/"+wallet.ID.String(), nil, &rest.HTTPResponse{Data: wallet})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Equal(`"2"`, resp.Header.Get("ETag"))
+		s.Require().Equal(int64(2), wallet.Version)
+	})
+
+	s.Run("412/stale version", func() {
+		resp := s.sendConditionalRequest(ctx, http.MethodPut, "/withdraw", `"1"`, withdrawal)
+		s.Require().Equal(http.StatusPreconditionFailed, resp.StatusCode)
+
+		read, err := s.service.GetWallet(models.WithReadYourWrites(ctx), wallet.ID)
+		s.Require().NoError(err)
+		s.Require().Equal(10.0, read.Balance)
+	})
+
+	s.Run("412/weak tag", func() {
+		resp := s.sendConditionalRequest(ctx, http.MethodPut, "/withdraw", `W/"2"`, withdrawal)
+		s.Require().Equal(http.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	s.Run("412/transfer with a stale version", func() {
+		target := new(models.Wallet)
+		resp := s.sendRequest(ctx, http.MethodPost, "/", nil, &rest.HTTPResponse{Data: target})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+		resp = s.sendConditionalRequest(ctx, http.MethodPost, "/"+wallet.ID.String()+"/transfers", `"1"`,
+			rest.TransferRequest{TargetWalletID: target.ID, Amount: 4})
+		s.Require().Equal(http.StatusPreconditionFailed, resp.StatusCode)
+	})
+
+	s.Run("any of the listed versions", func() {
+		resp := s.sendConditionalRequest(ctx, http.MethodPut, "/withdraw", `"1", "2"`, withdrawal)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	s.Run("any version", func() {
+		resp := s.sendConditionalRequest(ctx, http.MethodPut, "/withdraw", "*", withdrawal)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	s.Run("404/unknown wallet", func() {
+		unknown := models.Transaction{WalletID: [16]byte{1}, Amount: 1, OperationType: models.OperationDeposit}
+
+		resp := s.sendConditionalRequest(ctx, http.MethodPut, "/deposit", `"1"`, unknown)
+		s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	s.Run("administrative changes bump the version", func() {
+		frozen, err := s.service.SetWalletFrozen(ctx, wallet.ID, true)
+		s.Require().NoError(err)
+		s.Require().Equal(int64(5), frozen.Version)
+	})
+}
+
+func (s *IntegrationTestSuite) sendConditionalRequest(ctx context.Context, method, endpoint, ifMatch string, body any) *http.Response {
+	s.T().Helper()
+
+	reqBody, err := json.Marshal(body)
+	s.Require().NoError(err)
+
+	req, err := http.NewRequestWithContext(ctx, method, bindAddress+endpoint, bytes.NewBuffer(reqBody))
+	s.Require().NoError(err)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", ifMatch)
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+
+	return resp
+}
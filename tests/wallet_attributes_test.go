@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/iurikman/wallets/internal/models"
+	"github.com/iurikman/wallets/internal/rest"
+)
+
+func (s *IntegrationTestSuite) TestWalletAttributes() {
+	ctx := context.Background()
+
+	// Labels and references are unique to the test, so wallets of other tests are never found.
+	label := "test-" + uuid.NewString()
+	externalRef := "acc-" + uuid.NewString()
+
+	wallet := new(models.Wallet)
+
+	s.Run("201/create with attributes", func() {
+		resp := s.sendRequest(ctx, http.MethodPost, "/", map[string]any{
+			"externalRef": externalRef,
+			"labels":      []string{label, "merchant"},
+			"metadata":    map[string]any{"name": "Shop"},
+		}, &rest.HTTPResponse{Data: wallet})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+		s.Require().Equal(externalRef, wallet.ExternalRef)
+		s.Require().Equal([]string{label, "merchant"}, wallet.Labels)
+		s.Require().JSONEq(`{"name": "Shop"}`, string(wallet.Metadata))
+	})
+
+	s.Run("409/external reference taken", func() {
+		var httpResp rest.HTTPResponse
+
+		resp := s.sendRequest(ctx, http.MethodPost, "/", map[string]any{"externalRef": externalRef}, &httpResp)
+		s.Require().Equal(http.StatusConflict, resp.StatusCode)
+		s.Require().Equal(rest.ErrCodeExternalRefTaken, httpResp.Error.Code)
+	})
+
+	s.Run("400/metadata too large", func() {
+		var httpResp rest.HTTPResponse
+
+		resp := s.sendRequest(ctx, http.MethodPost, "/", map[string]any{
+			"metadata": map[string]any{"note": strings.Repeat("a", models.MaxMetadataBytes)},
+		}, &httpResp)
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Require().Equal([]rest.FieldError{{Field: "metadata", Message: models.ErrMetadataTooLarge.Error()}}, httpResp.Error.Details)
+	})
+
+	s.Run("200/patch replaces the given attributes", func() {
+		updated := new(models.Wallet)
+
+		resp := s.sendRequest(ctx, http.MethodPatch, "/"+wallet.ID.String(), map[string]any{"labels": []string{label}},
+			&rest.HTTPResponse{Data: updated})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Equal(wallet.Version+1, updated.Version)
+		s.Require().Equal(externalRef, updated.ExternalRef)
+		s.Require().Equal([]string{label}, updated.Labels)
+		s.Require().JSONEq(`{"name": "Shop"}`, string(updated.Metadata))
+	})
+
+	s.Run("404/patch unknown wallet", func() {
+		resp := s.sendRequest(ctx, http.MethodPatch, "/"+uuid.NewString(), map[string]any{"labels": []string{}}, nil)
+		s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	s.Run("200/search", func() {
+		other := new(models.Wallet)
+
+		resp := s.sendRequest(ctx, http.MethodPost, "/", map[string]any{"labels": []string{label}}, &rest.HTTPResponse{Data: other})
+		s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+		var page rest.WalletsPage
+
+		resp = s.sendAdminRequest(ctx, http.MethodGet, "/wallets?label="+label, testAdminAPIKey, &rest.HTTPResponse{Data: &page})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Len(page.Wallets, 2)
+		s.Require().Equal(wallet.ID, page.Wallets[0].ID)
+		s.Require().Equal(other.ID, page.Wallets[1].ID)
+
+		resp = s.sendAdminRequest(ctx, http.MethodGet, "/wallets?limit=1&label="+label, testAdminAPIKey, &rest.HTTPResponse{Data: &page})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Len(page.Wallets, 1)
+		s.Require().NotEmpty(page.NextCursor)
+
+		resp = s.sendAdminRequest(ctx, http.MethodGet, "/wallets?limit=1&label="+label+"&cursor="+url.QueryEscape(page.NextCursor),
+			testAdminAPIKey, &rest.HTTPResponse{Data: &page})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Len(page.Wallets, 1)
+		s.Require().Equal(other.ID, page.Wallets[0].ID)
+		s.Require().Empty(page.NextCursor)
+
+		resp = s.sendAdminRequest(ctx, http.MethodGet, "/wallets?external_ref="+externalRef, testAdminAPIKey, &rest.HTTPResponse{Data: &page})
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().Len(page.Wallets, 1)
+		s.Require().Equal(wallet.ID, page.Wallets[0].ID)
+	})
+
+	s.Run("401/search without API key", func() {
+		resp := s.sendAdminRequest(ctx, http.MethodGet, "/wallets?label="+label, "", nil)
+		s.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	s.Run("v2 leaves metadata as it is", func() {
+		var httpResp struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+
+		resp := s.sendV2Request(ctx, http.MethodPatch, "/wallets/"+wallet.ID.String(), map[string]any{"metadata": map[string]any{"amount": "1"}},
+			&httpResp)
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().JSONEq(`{"amount": "1"}`, string(httpResp.Data["Metadata"]))
+	})
+}
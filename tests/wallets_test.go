@@ -51,7 +51,7 @@ func (s *IntegrationTestSuite) TestWallets() {
 	s.Run("PUT", func() {
 		s.Run("/deposit", func() {
 			s.Run("200/statusOK", func() {
-				executedTransaction := new(models.Transaction)
+				executedTransaction := new(models.TransactionResult)
 
 				testDepositOperation := models.Transaction{
 					TransactionID: uuid.New(),
@@ -68,6 +68,12 @@ func (s *IntegrationTestSuite) TestWallets() {
 					&rest.HTTPResponse{Data: &executedTransaction},
 				)
 				s.Require().Equal(http.StatusOK, resp.StatusCode)
+				s.Require().NotEqual(uuid.Nil, executedTransaction.TransactionID)
+				s.Require().Equal(500.0, executedTransaction.BalanceAfter)
+				s.Require().Equal(
+					"/api/v1/wallets/"+testWalletID.String()+"/transactions/"+executedTransaction.TransactionID.String(),
+					resp.Header.Get("Location"),
+				)
 			})
 
 			s.Run("404/StatusNotFound(random wallet id)", func() {
@@ -101,7 +107,7 @@ func (s *IntegrationTestSuite) TestWallets() {
 		})
 		s.Run("/withdraw", func() {
 			s.Run("200/statusOK", func() {
-				executedTransaction := new(models.Transaction)
+				executedTransaction := new(models.TransactionResult)
 
 				testDepositOperation := models.Transaction{
 					TransactionID: uuid.New(),
@@ -118,6 +124,7 @@ func (s *IntegrationTestSuite) TestWallets() {
 					&rest.HTTPResponse{Data: &executedTransaction},
 				)
 				s.Require().Equal(http.StatusOK, resp.StatusCode)
+				s.Require().Equal(250.0, executedTransaction.BalanceAfter)
 			})
 
 			s.Run("404/StatusBadRequest(operation type not allowed", func() {
@@ -166,14 +173,17 @@ func (s *IntegrationTestSuite) TestWallets() {
 					OperationType: "DEPOSIT",
 				}
 
+				respBody := new(rest.HTTPResponse)
+
 				resp := s.sendRequest(
 					context.Background(),
 					http.MethodPut,
 					"/withdraw",
 					testDepositOperation,
-					nil,
+					respBody,
 				)
 				s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+				s.Require().Equal(rest.ErrCodeInsufficientFunds, respBody.Error.Code)
 			})
 
 			s.Run("400/StatusBadRequest(bad request)", func() {